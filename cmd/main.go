@@ -6,9 +6,11 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"time"
 
 	graph "github.com/aashi1008/hamburg-rails/internal/graphs"
 	"github.com/aashi1008/hamburg-rails/internal/handlers"
+	"github.com/aashi1008/hamburg-rails/internal/metrics"
 	"github.com/aashi1008/hamburg-rails/internal/server"
 )
 
@@ -17,18 +19,34 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 
 	graphPath := flag.String("graph", "", "Path to the graph file")
+	useCH := flag.Bool("ch", false, "Build contraction hierarchies after every graph load")
+	mirror := flag.Bool("mirror", false, "Load every edge of the graph file in both directions")
+	workers := flag.Int("workers", 1, "Goroutines used to enumerate routes for the count and search endpoints")
 	flag.Parse()
 
 	g := graph.NewGraph()
+	if *useCH {
+		g.EnableContractionHierarchies(func(d time.Duration) {
+			metrics.GraphCHPreprocessingSeconds.Set(d.Seconds())
+			logger.Info("contraction hierarchy ready", "duration_ms", d.Milliseconds())
+		})
+	}
 	if *graphPath != "" {
 		fmt.Println("Graph file path:", *graphPath)
-		err := g.LoadGraphFromFile(*graphPath)
+		report, err := g.ImportFile(*graphPath, graph.ImportOptions{Mirror: *mirror})
 		if err != nil {
 			log.Fatalf("failed to load graph from file: %v", err)
 		}
+		for _, w := range report.Warnings {
+			logger.Warn("skipped graph input", "file", w.File, "line", w.Line, "message", w.Message)
+		}
+		if report.AsymmetricCount > 0 {
+			logger.Info("graph has one-way edges", "asymmetric_pairs", report.AsymmetricCount)
+		}
 	}
 
 	h := handlers.NewHandler(g)
+	h.Enum = graph.EnumOptions{Workers: *workers}
 
 	server.StartServer(":8080", h, logger)
 }
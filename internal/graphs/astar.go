@@ -0,0 +1,165 @@
+package graphs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Algorithm selects the point-to-point shortest path search.
+type Algorithm string
+
+const (
+	AlgorithmDijkstra Algorithm = "dijkstra"
+	// AlgorithmBidirectional searches forwards from the origin and backwards
+	// from the destination at the same time, meeting in the middle.
+	AlgorithmBidirectional Algorithm = "bidirectional"
+	// AlgorithmAStar uses town coordinates as a great-circle lower bound. It
+	// falls back to Dijkstra when some town has no coordinates, the graph's
+	// distances are times or the bound is not admissible for the graph.
+	AlgorithmAStar Algorithm = "astar"
+)
+
+// ParseAlgorithm validates an algorithm name, defaulting to Dijkstra when empty
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch Algorithm(s) {
+	case "", AlgorithmDijkstra:
+		return AlgorithmDijkstra, nil
+	case AlgorithmAStar:
+		return AlgorithmAStar, nil
+	case AlgorithmBidirectional:
+		return AlgorithmBidirectional, nil
+	case AlgorithmCH:
+		return AlgorithmCH, nil
+	case AlgorithmALT:
+		return AlgorithmALT, nil
+	}
+	return "", fmt.Errorf("invalid algorithm: %q", s)
+}
+
+// Coordinate is a town position in decimal degrees.
+type Coordinate struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+const earthRadiusKm = 6371.0088
+
+// greatCircleKm returns the haversine distance between two coordinates.
+func greatCircleKm(a, b Coordinate) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// SetCoordinates replaces the town coordinates. Towns need not exist in the
+// current graph, and coordinates survive graph reloads.
+func (g *Graph) SetCoordinates(coords map[string]Coordinate) error {
+	copied := make(map[string]Coordinate, len(coords))
+	for town, c := range coords {
+		if c.Lat < -90 || c.Lat > 90 || c.Lon < -180 || c.Lon > 180 {
+			return fmt.Errorf("invalid coordinate for town %q", town)
+		}
+		copied[town] = c
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.coords = copied
+	g.kmSteps = heuristicSteps(g.data, g.coords, g.meta.Units)
+	return nil
+}
+
+// Coordinates returns a copy of the town coordinates
+func (g *Graph) Coordinates() map[string]Coordinate {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	out := make(map[string]Coordinate, len(g.coords))
+	for k, v := range g.coords {
+		out[k] = v
+	}
+	return out
+}
+
+// heuristicSteps returns how many stored distance steps make a kilometre
+// when the great-circle distance is a consistent lower bound on any route:
+// the graph's units are lengths, taken to be kilometres when it declares
+// none, every town has coordinates and no edge is shorter than the
+// great-circle distance between its towns. It returns 0 otherwise.
+func heuristicSteps(c *csr, coords map[string]Coordinate, units string) float64 {
+	if len(coords) == 0 {
+		return 0
+	}
+	perKm := float64(c.scale)
+	if units != "" {
+		u := distanceUnits[units]
+		if u.kind != "length" {
+			return 0
+		}
+		perKm *= 1000 / u.base
+	}
+	for v, name := range c.names {
+		a, ok := coords[name]
+		if !ok {
+			return 0
+		}
+		lo, hi := c.out(v)
+		for i := lo; i < hi; i++ {
+			b, ok := coords[c.names[c.targets[i]]]
+			if !ok || float64(c.dists[i]) < greatCircleKm(a, b)*perKm {
+				return 0
+			}
+		}
+	}
+	return perKm
+}
+
+// ShortestPathWith returns the shortest distance and path using the given
+// algorithm, along with statistics about the search. Every algorithm
+// returns the same distance.
+func (g *Graph) ShortestPathWith(from, to string, alg Algorithm) (int, []string, SearchStats) {
+	start := time.Now()
+	stats := SearchStats{Algorithm: AlgorithmDijkstra}
+	dist, path := g.shortestPathWith(from, to, alg, &stats)
+	stats.timed(start)
+	return dist, path, stats
+}
+
+func (g *Graph) shortestPathWith(from, to string, alg Algorithm, stats *SearchStats) (int, []string) {
+	g.mutex.RLock()
+	c, coords, kmSteps, ch := g.data, g.coords, g.kmSteps, g.ch
+	g.mutex.RUnlock()
+
+	src, okFrom := c.id(from)
+	dst, okTo := c.id(to)
+	if !okFrom || !okTo {
+		return -1, nil
+	}
+	var dist int
+	var path []int
+	switch {
+	case alg == AlgorithmBidirectional && src != dst:
+		stats.Algorithm = AlgorithmBidirectional
+		dist, path = bidirectionalSearch(c, src, dst, stats)
+	case alg == AlgorithmCH && src != dst && ch != nil:
+		stats.Algorithm = AlgorithmCH
+		return ch.shortestPath(from, to, stats)
+	case alg == AlgorithmAStar && kmSteps > 0:
+		target := coords[to]
+		stats.Algorithm = AlgorithmAStar
+		h := func(v int) int {
+			return int(math.Floor(greatCircleKm(coords[c.names[v]], target) * kmSteps))
+		}
+		dist, path = shortestPathSearch(c, src, dst, h, stats)
+	case alg == AlgorithmALT && c.alt != nil:
+		stats.Algorithm = AlgorithmALT
+		dist, path = shortestPathSearch(c, src, dst, c.alt.heuristic(dst), stats)
+	default:
+		dist, path = shortestPathSearch(c, src, dst, nil, stats)
+	}
+	if dist == -1 {
+		return -1, nil
+	}
+	return dist, c.towns(path)
+}
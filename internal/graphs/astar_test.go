@@ -0,0 +1,121 @@
+package graphs
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gridGraph builds a 5x5 grid of towns A..Y around Hamburg with two-way
+// edges slightly longer than the great-circle distance between neighbours.
+func gridGraph(t *testing.T) *Graph {
+	coords := make(map[string]Coordinate)
+	name := func(r, c int) string { return string(rune('A' + r*5 + c)) }
+	for r := 0; r < 5; r++ {
+		for c := 0; c < 5; c++ {
+			coords[name(r, c)] = Coordinate{Lat: 53.5 + 0.1*float64(r), Lon: 10.0 + 0.1*float64(c)}
+		}
+	}
+	var edges []string
+	link := func(a, b string) {
+		d := int(math.Ceil(greatCircleKm(coords[a], coords[b]))) + 1
+		edges = append(edges, fmt.Sprintf("%s%s%d", a, b, d), fmt.Sprintf("%s%s%d", b, a, d))
+	}
+	for r := 0; r < 5; r++ {
+		for c := 0; c < 5; c++ {
+			if c < 4 {
+				link(name(r, c), name(r, c+1))
+			}
+			if r < 4 {
+				link(name(r, c), name(r+1, c))
+			}
+		}
+	}
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges(edges))
+	assert.NoError(t, g.SetCoordinates(coords))
+	return g
+}
+
+func TestAStarExpandsFewerNodes(t *testing.T) {
+	g := gridGraph(t)
+
+	dDist, dPath, dStats := g.ShortestPathWith("A", "E", AlgorithmDijkstra)
+	aDist, aPath, aStats := g.ShortestPathWith("A", "E", AlgorithmAStar)
+	assert.Equal(t, AlgorithmAStar, aStats.Algorithm)
+	assert.Equal(t, dDist, aDist)
+	assert.Len(t, aPath, len(dPath))
+	assert.Less(t, aStats.NodesExpanded, dStats.NodesExpanded)
+
+	d, err := g.Distance(aPath)
+	assert.NoError(t, err)
+	assert.Equal(t, aDist, d)
+}
+
+// unitGridGraph is gridGraph with its edges written in the given unit to
+// the thousandth.
+func unitGridGraph(t *testing.T, unit string, perKm float64) *Graph {
+	grid := gridGraph(t)
+	coords := grid.Coordinates()
+	var text strings.Builder
+	fmt.Fprintf(&text, "@units %s\n", unit)
+	for from, edges := range grid.Nodes() {
+		for _, e := range edges {
+			d := math.Ceil(greatCircleKm(coords[from], coords[e.To])*perKm*1000)/1000 + 0.001
+			fmt.Fprintf(&text, "%s->%s:%.3f\n", from, e.To, d)
+		}
+	}
+	g := NewGraph()
+	_, err := g.ImportWithOptions(strings.NewReader(text.String()), FormatText, ImportOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, g.SetCoordinates(coords))
+	return g
+}
+
+func TestAStarScaledDistances(t *testing.T) {
+	_, _, whole := gridGraph(t).ShortestPathWith("A", "E", AlgorithmAStar)
+	for _, tt := range []struct {
+		unit  string
+		perKm float64
+	}{
+		{"km", 1},
+		{"m", 1000},
+		{"mi", 1 / 1.609344},
+	} {
+		g := unitGridGraph(t, tt.unit, tt.perKm)
+		assert.Equal(t, 1000, g.DistanceScale(), tt.unit)
+		dDist, _, dStats := g.ShortestPathWith("A", "E", AlgorithmDijkstra)
+		aDist, _, aStats := g.ShortestPathWith("A", "E", AlgorithmAStar)
+		assert.Equal(t, AlgorithmAStar, aStats.Algorithm, tt.unit)
+		assert.Equal(t, dDist, aDist, tt.unit)
+		// the bound is as tight as on whole kilometres, not 1000 times weaker
+		assert.Less(t, aStats.NodesExpanded, dStats.NodesExpanded, tt.unit)
+		assert.LessOrEqual(t, aStats.NodesExpanded, whole.NodesExpanded, tt.unit)
+	}
+
+	// times cannot be bounded by a distance
+	_, _, stats := unitGridGraph(t, "min", 1).ShortestPathWith("A", "E", AlgorithmAStar)
+	assert.Equal(t, AlgorithmDijkstra, stats.Algorithm)
+}
+
+func TestAStarDisabledWhenNotAdmissible(t *testing.T) {
+	g := gridGraph(t)
+	coords := g.Coordinates()
+	// moving Y far away makes every edge into it shorter than the heuristic
+	coords["Y"] = Coordinate{Lat: 10, Lon: 10}
+	assert.NoError(t, g.SetCoordinates(coords))
+
+	_, _, stats := g.ShortestPathWith("A", "Y", AlgorithmAStar)
+	assert.Equal(t, AlgorithmDijkstra, stats.Algorithm)
+
+	// seed graph has no coordinates at all
+	dist, path, stats := seedGraph().ShortestPathWith("A", "C", AlgorithmAStar)
+	assert.Equal(t, AlgorithmDijkstra, stats.Algorithm)
+	assert.Equal(t, 9, dist)
+	assert.Equal(t, []string{"A", "B", "C"}, path)
+
+	assert.Error(t, g.SetCoordinates(map[string]Coordinate{"A": {Lat: 91}}))
+}
@@ -0,0 +1,105 @@
+package graphs
+
+import (
+	"container/heap"
+)
+
+// searchSide is one half of a bidirectional search: the forward half walks
+// outgoing edges from the origin, the backward half incoming edges from
+// the destination.
+type searchSide struct {
+	dist    []int
+	parent  []int32
+	settled []bool
+	pq      *priorityQueue
+}
+
+func newSearchSide(n, start int) *searchSide {
+	s := &searchSide{
+		dist:    make([]int, n),
+		parent:  make([]int32, n),
+		settled: make([]bool, n),
+		pq:      &priorityQueue{},
+	}
+	for i := range s.dist {
+		s.dist[i] = -1
+	}
+	s.dist[start] = 0
+	heap.Push(s.pq, pqItem{node: int32(start), parent: -1})
+	return s
+}
+
+// top returns the smallest key still queued.
+func (s *searchSide) top() int {
+	return (*s.pq)[0].dist
+}
+
+// bidirectionalSearch runs Dijkstra from both ends and stops once the two
+// frontiers together cannot improve on the best meeting point. Towns keep a
+// parent pointer instead of a copy of their path. from and to must differ;
+// shortest cycles are left to the one-sided search.
+func bidirectionalSearch(c *csr, from, to int, stats *SearchStats) (int, []int) {
+	if from == to {
+		return -1, nil
+	}
+	fwd := newSearchSide(c.size(), from)
+	bwd := newSearchSide(c.size(), to)
+	stats.push()
+	stats.push()
+
+	best, meet := -1, -1
+	for fwd.pq.Len() > 0 && bwd.pq.Len() > 0 {
+		if best != -1 && fwd.top()+bwd.top() >= best {
+			break
+		}
+		side, other, forward := fwd, bwd, true
+		if bwd.pq.Len() < fwd.pq.Len() {
+			side, other, forward = bwd, fwd, false
+		}
+		curr := heap.Pop(side.pq).(pqItem)
+		if side.settled[curr.node] {
+			continue
+		}
+		side.settled[curr.node] = true
+		stats.expand()
+
+		lo, hi := c.out(int(curr.node))
+		targets, dists := c.targets, c.dists
+		if !forward {
+			lo, hi = c.in(int(curr.node))
+			targets, dists = c.inSource, c.inDists
+		}
+		for i := lo; i < hi; i++ {
+			next := targets[i]
+			nd := curr.dist + dists[i]
+			stats.relax()
+			if d := side.dist[next]; d >= 0 && d <= nd {
+				continue
+			}
+			side.dist[next] = nd
+			side.parent[next] = curr.node
+			heap.Push(side.pq, pqItem{node: next, dist: nd})
+			stats.push()
+			if od := other.dist[next]; od >= 0 && (best == -1 || nd+od < best) {
+				best, meet = nd+od, int(next)
+			}
+		}
+	}
+	if best == -1 {
+		return -1, nil
+	}
+
+	path := []int{meet}
+	for cur := meet; cur != from; {
+		cur = int(fwd.parent[cur])
+		path = append(path, cur)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	for cur := meet; cur != to; {
+		cur = int(bwd.parent[cur])
+		path = append(path, cur)
+	}
+	return best, path
+}
@@ -0,0 +1,55 @@
+package graphs
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBidirectionalMatchesDijkstraOnSeed(t *testing.T) {
+	g := seedGraph()
+	towns := []string{"A", "B", "C", "D", "E"}
+	for _, from := range towns {
+		for _, to := range towns {
+			dDist, dPath := g.ShortestPath(from, to)
+			bDist, bPath, stats := g.ShortestPathWith(from, to, AlgorithmBidirectional)
+			assert.Equal(t, dDist, bDist, "%s->%s", from, to)
+			assert.Equal(t, dPath, bPath, "%s->%s", from, to)
+			if from != to {
+				assert.Equal(t, AlgorithmBidirectional, stats.Algorithm)
+			}
+		}
+	}
+}
+
+func TestBidirectionalMatchesDijkstraOnRandomGraphs(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	for round := 0; round < 20; round++ {
+		var edges []string
+		for from := 'A'; from <= 'T'; from++ {
+			for to := 'A'; to <= 'T'; to++ {
+				if from != to && rng.Intn(5) == 0 {
+					edges = append(edges, fmt.Sprintf("%c%c%d", from, to, 1+rng.Intn(1000)))
+				}
+			}
+		}
+		g := NewGraph()
+		assert.NoError(t, g.LoadEdges(edges))
+		for q := 0; q < 20; q++ {
+			from, to := string(rune('A'+rng.Intn(20))), string(rune('A'+rng.Intn(20)))
+			dDist, dPath := g.ShortestPath(from, to)
+			bDist, bPath, _ := g.ShortestPathWith(from, to, AlgorithmBidirectional)
+			assert.Equal(t, dDist, bDist, "%s->%s", from, to)
+			if from != to {
+				assert.Equal(t, dPath, bPath, "%s->%s", from, to)
+			}
+			if bDist != -1 {
+				d, err := g.Distance(bPath)
+				assert.NoError(t, err)
+				assert.Equal(t, bDist, d)
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package graphs
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+// BottleneckMode selects which limiting edge a bottleneck search optimises.
+type BottleneckMode string
+
+const (
+	// Minimax finds the route whose longest single leg is as short as possible.
+	Minimax BottleneckMode = "minimax"
+	// Maximin finds the route whose smallest leg capacity is as large as possible.
+	Maximin BottleneckMode = "maximin"
+)
+
+// ParseBottleneckMode validates a mode name, defaulting to Minimax when empty
+func ParseBottleneckMode(s string) (BottleneckMode, error) {
+	switch BottleneckMode(s) {
+	case "", Minimax:
+		return Minimax, nil
+	case Maximin:
+		return Maximin, nil
+	}
+	return "", fmt.Errorf("invalid bottleneck mode: %q", s)
+}
+
+// Leg is a single directed edge of a route. Capacity is DefaultCapacity for
+// edges loaded without one.
+type Leg struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Distance int    `json:"distance"`
+	Capacity int    `json:"capacity"`
+}
+
+// weight returns the value of edge slot i the bottleneck is measured on:
+// distance for Minimax and capacity for Maximin.
+func (m BottleneckMode) weight(c *csr, i int) int {
+	if m == Maximin {
+		return c.capacity(i)
+	}
+	return c.dists[i]
+}
+
+// better reports whether bottleneck value a beats b.
+func (m BottleneckMode) better(a, b int) bool {
+	if m == Maximin {
+		return a > b
+	}
+	return a < b
+}
+
+// extend returns the bottleneck of a route with value b after taking an edge of weight w.
+func (m BottleneckMode) extend(b, w int) int {
+	if m == Maximin {
+		return min(b, w)
+	}
+	return max(b, w)
+}
+
+// BottleneckPath returns the route from one town to another whose limiting
+// edge is optimal for mode. Among routes sharing the optimal bottleneck the
+// one with the smallest total distance is returned. ok is false when no
+// route exists.
+func (g *Graph) BottleneckPath(from, to string, mode BottleneckMode) (path []string, distance int, limit Leg, ok bool) {
+	return g.BottleneckPathWithStats(from, to, mode, nil)
+}
+
+// BottleneckPathWithStats is BottleneckPath recording the work of both
+// passes in stats, which may be nil.
+func (g *Graph) BottleneckPathWithStats(from, to string, mode BottleneckMode, stats *SearchStats) (path []string, distance int, limit Leg, ok bool) {
+	defer stats.timed(time.Now())
+	c := g.snapshot()
+	src, okFrom := c.id(from)
+	dst, okTo := c.id(to)
+	if !okFrom || !okTo {
+		return nil, -1, Leg{}, false
+	}
+
+	// First pass: a Dijkstra variant ordered on the bottleneck value finds
+	// the best achievable limit.
+	settled := make([]bool, c.size())
+	pq := &bottleneckQueue{mode: mode}
+	lo, hi := c.out(src)
+	for i := lo; i < hi; i++ {
+		stats.relax()
+		heap.Push(pq, bottleneckItem{node: int(c.targets[i]), value: mode.weight(c, i)})
+		stats.push()
+	}
+	found := false
+	bound := 0
+	for pq.Len() > 0 {
+		curr := heap.Pop(pq).(bottleneckItem)
+		if settled[curr.node] {
+			continue
+		}
+		settled[curr.node] = true
+		stats.expand()
+		if curr.node == dst {
+			found = true
+			bound = curr.value
+			break
+		}
+		lo, hi := c.out(curr.node)
+		for i := lo; i < hi; i++ {
+			stats.relax()
+			if !settled[c.targets[i]] {
+				heap.Push(pq, bottleneckItem{node: int(c.targets[i]), value: mode.extend(curr.value, mode.weight(c, i))})
+				stats.push()
+			}
+		}
+	}
+	if !found {
+		return nil, -1, Leg{}, false
+	}
+
+	// Second pass: the shortest route using only edges that do not break
+	// the bound.
+	allow := func(i int) bool {
+		return !mode.better(bound, mode.weight(c, i))
+	}
+	distance, ids := restrictedShortestPath(c, src, dst, allow, stats)
+	if ids == nil {
+		return nil, -1, Leg{}, false
+	}
+
+	path = c.towns(ids)
+	for k := 0; k < len(ids)-1; k++ {
+		if i, _ := c.find(ids[k], ids[k+1]); mode.weight(c, i) == bound {
+			return path, distance, Leg{From: path[k], To: path[k+1], Distance: c.dists[i], Capacity: c.capacity(i)}, true
+		}
+	}
+	return path, distance, Leg{}, true
+}
+
+// restrictedShortestPath runs Dijkstra over the edge slots accepted by allow.
+// The route always has at least one edge, so from == to yields the shortest
+// cycle. Edges refused by allow are counted as PruneBottleneck.
+func restrictedShortestPath(c *csr, from, to int, allow func(int) bool, stats *SearchStats) (int, []int) {
+	settled := make([]bool, c.size())
+	parent := make([]int32, c.size())
+	pq := &priorityQueue{}
+	lo, hi := c.out(from)
+	for i := lo; i < hi; i++ {
+		stats.relax()
+		if !allow(i) {
+			stats.prune(PruneBottleneck)
+			continue
+		}
+		heap.Push(pq, pqItem{node: c.targets[i], dist: c.dists[i], parent: int32(from)})
+		stats.push()
+	}
+	for pq.Len() > 0 {
+		curr := heap.Pop(pq).(pqItem)
+		if settled[curr.node] {
+			continue
+		}
+		settled[curr.node] = true
+		parent[curr.node] = curr.parent
+		stats.expand()
+		if int(curr.node) == to {
+			return curr.dist, pathTo(parent, from, to)
+		}
+		lo, hi := c.out(int(curr.node))
+		for i := lo; i < hi; i++ {
+			if settled[c.targets[i]] {
+				continue
+			}
+			stats.relax()
+			if !allow(i) {
+				stats.prune(PruneBottleneck)
+				continue
+			}
+			heap.Push(pq, pqItem{node: c.targets[i], dist: curr.dist + c.dists[i], parent: curr.node})
+			stats.push()
+		}
+	}
+	return -1, nil
+}
+
+type bottleneckItem struct {
+	node  int
+	value int
+}
+
+type bottleneckQueue struct {
+	mode  BottleneckMode
+	items []bottleneckItem
+}
+
+func (q bottleneckQueue) Len() int { return len(q.items) }
+func (q bottleneckQueue) Less(i, j int) bool {
+	return q.mode.better(q.items[i].value, q.items[j].value)
+}
+func (q bottleneckQueue) Swap(i, j int)       { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *bottleneckQueue) Push(x interface{}) { q.items = append(q.items, x.(bottleneckItem)) }
+func (q *bottleneckQueue) Pop() interface{} {
+	old := q.items
+	n := len(old)
+	item := old[n-1]
+	q.items = old[:n-1]
+	return item
+}
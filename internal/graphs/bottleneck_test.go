@@ -0,0 +1,53 @@
+package graphs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBottleneckMinimax(t *testing.T) {
+	g := seedGraph()
+
+	path, dist, limit, ok := g.BottleneckPath("A", "C", Minimax)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"A", "B", "C"}, path)
+	assert.Equal(t, 9, dist)
+	assert.Equal(t, Leg{From: "A", To: "B", Distance: 5, Capacity: DefaultCapacity}, limit)
+
+	// C->C cycles: C-D-C has a leg of 8, C-E-B-C never exceeds 4
+	path, dist, limit, ok = g.BottleneckPath("C", "C", Minimax)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"C", "E", "B", "C"}, path)
+	assert.Equal(t, 9, dist)
+	assert.Equal(t, 4, limit.Distance)
+}
+
+func TestBottleneckMaximin(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB5/2", "BC4/10", "AD5/6", "DC8/4", "AE7/9", "EC9/5"}))
+
+	path, dist, limit, ok := g.BottleneckPath("A", "C", Maximin)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"A", "E", "C"}, path)
+	assert.Equal(t, 16, dist)
+	assert.Equal(t, Leg{From: "E", To: "C", Distance: 9, Capacity: 5}, limit)
+
+	// without capacities every edge carries DefaultCapacity, so the
+	// shortest route wins
+	path, _, limit, ok = seedGraph().BottleneckPath("A", "C", Maximin)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"A", "B", "C"}, path)
+	// and that is the capacity the limiting edge reports
+	assert.Equal(t, Leg{From: "A", To: "B", Distance: 5, Capacity: DefaultCapacity}, limit)
+}
+
+func TestBottleneckNoRoute(t *testing.T) {
+	g := seedGraph()
+
+	_, _, _, ok := g.BottleneckPath("C", "A", Minimax)
+	assert.False(t, ok)
+
+	_, err := ParseBottleneckMode("fastest")
+	assert.Error(t, err)
+}
@@ -0,0 +1,344 @@
+package graphs
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// AlgorithmCH answers queries from the contraction hierarchy when one has
+// been built for the current graph, and falls back to Dijkstra otherwise.
+const AlgorithmCH Algorithm = "ch"
+
+// witnessSettleLimit bounds each witness search during contraction. A search
+// that gives up early only costs an unnecessary shortcut, never correctness.
+const witnessSettleLimit = 500
+
+// chArc is an edge of the hierarchy. mid is the contracted town a shortcut
+// bypasses, or -1 for an original edge.
+type chArc struct {
+	to     int
+	weight int
+	mid    int
+}
+
+// contractionHierarchy is an immutable search structure for one version of
+// the graph. Queries only ever move to higher ranked towns, from both ends.
+type contractionHierarchy struct {
+	names []string
+	ids   map[string]int
+	// up[u] holds arcs u->w with rank[w] > rank[u]; down[w] holds arcs
+	// u->w with rank[u] > rank[w], stored at w with to = u.
+	up   [][]chArc
+	down [][]chArc
+	// mid records the bypassed town of every shortcut for path unpacking.
+	mid map[[2]int]int
+}
+
+// EnableContractionHierarchies makes every subsequent LoadEdges build a
+// contraction hierarchy in the background. Until it is ready, queries that
+// ask for AlgorithmCH run Dijkstra. onBuilt, if not nil, receives the
+// preprocessing time of each hierarchy that is swapped in.
+func (g *Graph) EnableContractionHierarchies(onBuilt func(time.Duration)) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.chEnabled = true
+	g.chBuilt = onBuilt
+	g.startHierarchyBuild()
+}
+
+// HierarchyEnabled reports whether contraction hierarchies are switched on.
+func (g *Graph) HierarchyEnabled() bool {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.chEnabled
+}
+
+// HierarchyReady reports whether a hierarchy for the current graph is in use.
+func (g *Graph) HierarchyReady() bool {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.ch != nil
+}
+
+// startHierarchyBuild discards the current hierarchy, cancels a build still
+// running for an older version and builds one for the current nodes in the
+// background. The caller must hold the write lock.
+func (g *Graph) startHierarchyBuild() {
+	g.version++
+	g.ch = nil
+	if g.chCancel != nil {
+		g.chCancel()
+		g.chCancel = nil
+	}
+	if !g.chEnabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	version, data, onBuilt := g.version, g.data, g.chBuilt
+	g.chCancel = cancel
+	go func() {
+		defer cancel()
+		start := time.Now()
+		ch, err := buildHierarchy(ctx, data)
+		if err != nil {
+			return
+		}
+		elapsed := time.Since(start)
+
+		g.mutex.Lock()
+		// a newer graph was loaded while building
+		current := g.version == version
+		if current {
+			g.ch = ch
+			g.chCancel = nil
+		}
+		g.mutex.Unlock()
+		if current && onBuilt != nil {
+			onBuilt(elapsed)
+		}
+	}()
+}
+
+// buildHierarchy contracts towns one at a time in order of edge difference
+// (shortcuts added minus edges removed, plus already contracted neighbours),
+// recomputing priorities lazily. It gives up with the context's error once
+// ctx is done.
+func buildHierarchy(ctx context.Context, c *csr) (*contractionHierarchy, error) {
+	names, ids := c.names, c.ids
+	n := len(names)
+	out := make([]map[int]int, n)
+	in := make([]map[int]int, n)
+	for v := range out {
+		out[v] = make(map[int]int)
+		in[v] = make(map[int]int)
+	}
+	for u := 0; u < n; u++ {
+		lo, hi := c.out(u)
+		for i := lo; i < hi; i++ {
+			w := int(c.targets[i])
+			out[u][w] = c.dists[i]
+			in[w][u] = c.dists[i]
+		}
+	}
+
+	ch := &contractionHierarchy{names: names, ids: ids, mid: make(map[[2]int]int)}
+	// every arc that ever existed, original or shortcut, keyed by its ends
+	arcs := make(map[[2]int]int)
+	for u := range out {
+		for w, d := range out[u] {
+			arcs[[2]int{u, w}] = d
+		}
+	}
+
+	contracted := make([]bool, n)
+	deleted := make([]int, n)
+	rank := make([]int, n)
+
+	// shortcuts returns the shortcuts needed to contract v.
+	shortcuts := func(v int) [][3]int {
+		var res [][3]int
+		for u, du := range in[v] {
+			if contracted[u] {
+				continue
+			}
+			limit := 0
+			for w, dw := range out[v] {
+				if !contracted[w] && w != u {
+					limit = max(limit, du+dw)
+				}
+			}
+			if limit == 0 {
+				continue
+			}
+			dist := witnessSearch(out, contracted, u, v, limit)
+			for w, dw := range out[v] {
+				if contracted[w] || w == u {
+					continue
+				}
+				if d, ok := dist[w]; ok && d <= du+dw {
+					continue
+				}
+				res = append(res, [3]int{u, w, du + dw})
+			}
+		}
+		return res
+	}
+	priority := func(v int) int {
+		removed := 0
+		for u := range in[v] {
+			if !contracted[u] {
+				removed++
+			}
+		}
+		for w := range out[v] {
+			if !contracted[w] {
+				removed++
+			}
+		}
+		return len(shortcuts(v)) - removed + deleted[v]
+	}
+
+	pq := &flowQueue{}
+	for v := 0; v < n; v++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		heap.Push(pq, flowItem{node: v, dist: priority(v)})
+	}
+	for order := 0; pq.Len() > 0; {
+		// every step runs witness searches, so check each time
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		v := heap.Pop(pq).(flowItem).node
+		if contracted[v] {
+			continue
+		}
+		if p := priority(v); pq.Len() > 0 && p > (*pq)[0].dist {
+			heap.Push(pq, flowItem{node: v, dist: p})
+			continue
+		}
+		for _, s := range shortcuts(v) {
+			u, w, d := s[0], s[1], s[2]
+			if old, ok := out[u][w]; ok && old <= d {
+				continue
+			}
+			out[u][w] = d
+			in[w][u] = d
+			arcs[[2]int{u, w}] = d
+			ch.mid[[2]int{u, w}] = v
+		}
+		contracted[v] = true
+		rank[v] = order
+		order++
+		for u := range in[v] {
+			deleted[u]++
+		}
+		for w := range out[v] {
+			deleted[w]++
+		}
+	}
+
+	ch.up = make([][]chArc, n)
+	ch.down = make([][]chArc, n)
+	for key, d := range arcs {
+		u, w := key[0], key[1]
+		mid, ok := ch.mid[key]
+		if !ok {
+			mid = -1
+		}
+		if rank[w] > rank[u] {
+			ch.up[u] = append(ch.up[u], chArc{to: w, weight: d, mid: mid})
+		} else {
+			ch.down[w] = append(ch.down[w], chArc{to: u, weight: d, mid: mid})
+		}
+	}
+	return ch, nil
+}
+
+// witnessSearch runs a bounded Dijkstra from u over uncontracted towns,
+// skipping v, and returns the distances it settled.
+func witnessSearch(out []map[int]int, contracted []bool, u, v, limit int) map[int]int {
+	dist := make(map[int]int)
+	pq := &flowQueue{}
+	heap.Push(pq, flowItem{node: u})
+	for pq.Len() > 0 && len(dist) < witnessSettleLimit {
+		curr := heap.Pop(pq).(flowItem)
+		if _, ok := dist[curr.node]; ok {
+			continue
+		}
+		if curr.dist > limit {
+			break
+		}
+		dist[curr.node] = curr.dist
+		for w, d := range out[curr.node] {
+			if w == v || contracted[w] {
+				continue
+			}
+			if _, ok := dist[w]; !ok {
+				heap.Push(pq, flowItem{node: w, dist: curr.dist + d})
+			}
+		}
+	}
+	return dist
+}
+
+// shortestPath answers a point-to-point query with an upward search from
+// both ends. It returns -1 when the towns are not connected.
+func (ch *contractionHierarchy) shortestPath(from, to string, stats *SearchStats) (int, []string) {
+	s, okFrom := ch.ids[from]
+	t, okTo := ch.ids[to]
+	if !okFrom || !okTo {
+		return -1, nil
+	}
+	fdist, fparent := ch.upwardSearch(s, ch.up, stats)
+	bdist, bparent := ch.upwardSearch(t, ch.down, stats)
+
+	best, meet := -1, -1
+	for v, df := range fdist {
+		if db, ok := bdist[v]; ok && (best == -1 || df+db < best || (df+db == best && v < meet)) {
+			best, meet = df+db, v
+		}
+	}
+	if best == -1 {
+		return -1, nil
+	}
+
+	var ids []int
+	for v := meet; v != s; v = fparent[v] {
+		ids = append([]int{v}, ids...)
+	}
+	ids = append([]int{s}, ids...)
+	for v := meet; v != t; {
+		v = bparent[v]
+		ids = append(ids, v)
+	}
+
+	path := []string{ch.names[ids[0]]}
+	for i := 0; i < len(ids)-1; i++ {
+		for _, v := range ch.unpack(ids[i], ids[i+1]) {
+			path = append(path, ch.names[v])
+		}
+	}
+	return best, path
+}
+
+// upwardSearch runs Dijkstra from src over the given half of the hierarchy.
+func (ch *contractionHierarchy) upwardSearch(src int, adj [][]chArc, stats *SearchStats) (map[int]int, map[int]int) {
+	dist := make(map[int]int)
+	parent := make(map[int]int)
+	pq := &flowQueue{}
+	heap.Push(pq, flowItem{node: src})
+	stats.push()
+	tentative := map[int]int{src: 0}
+	for pq.Len() > 0 {
+		curr := heap.Pop(pq).(flowItem)
+		if _, ok := dist[curr.node]; ok {
+			continue
+		}
+		dist[curr.node] = curr.dist
+		stats.expand()
+		for _, a := range adj[curr.node] {
+			nd := curr.dist + a.weight
+			stats.relax()
+			if d, ok := tentative[a.to]; ok && d <= nd {
+				continue
+			}
+			tentative[a.to] = nd
+			parent[a.to] = curr.node
+			heap.Push(pq, flowItem{node: a.to, dist: nd})
+			stats.push()
+		}
+	}
+	return dist, parent
+}
+
+// unpack expands the hierarchy arc u->w into the original towns after u.
+func (ch *contractionHierarchy) unpack(u, w int) []int {
+	mid, ok := ch.mid[[2]int{u, w}]
+	if !ok {
+		return []int{w}
+	}
+	return append(ch.unpack(u, mid), ch.unpack(mid, w)...)
+}
@@ -0,0 +1,106 @@
+package graphs
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHierarchyMatchesDijkstra(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for round := 0; round < 20; round++ {
+		var edges []string
+		for from := 'A'; from <= 'Z'; from++ {
+			for to := 'A'; to <= 'Z'; to++ {
+				if from != to && rng.Intn(6) == 0 {
+					edges = append(edges, fmt.Sprintf("%c%c%d", from, to, 1+rng.Intn(50)))
+				}
+			}
+		}
+		g := NewGraph()
+		assert.NoError(t, g.LoadEdges(edges))
+		ch, err := buildHierarchy(context.Background(), g.snapshot())
+		assert.NoError(t, err)
+		for from := 'A'; from <= 'Z'; from++ {
+			for to := 'A'; to <= 'Z'; to++ {
+				if from == to {
+					continue
+				}
+				want, _ := g.ShortestPath(string(from), string(to))
+				got, path := ch.shortestPath(string(from), string(to), nil)
+				assert.Equal(t, want, got, "%c->%c", from, to)
+				if got != -1 {
+					d, err := g.Distance(path)
+					assert.NoError(t, err)
+					assert.Equal(t, got, d)
+				}
+			}
+		}
+	}
+}
+
+func TestHierarchyBuiltInBackground(t *testing.T) {
+	g := seedGraph()
+	_, _, stats := g.ShortestPathWith("A", "C", AlgorithmCH)
+	assert.Equal(t, AlgorithmDijkstra, stats.Algorithm)
+
+	built := make(chan time.Duration, 1)
+	g.EnableContractionHierarchies(func(d time.Duration) { built <- d })
+	select {
+	case <-built:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hierarchy was not built")
+	}
+	assert.True(t, g.HierarchyReady())
+
+	dist, path, stats := g.ShortestPathWith("A", "C", AlgorithmCH)
+	assert.Equal(t, AlgorithmCH, stats.Algorithm)
+	assert.Equal(t, 9, dist)
+	assert.Equal(t, []string{"A", "B", "C"}, path)
+
+	// reloading discards the hierarchy until the new one is ready
+	assert.NoError(t, g.LoadEdges([]string{"AB1", "BC1"}))
+	<-built
+	dist, _, _ = g.ShortestPathWith("A", "C", AlgorithmCH)
+	assert.Equal(t, 2, dist)
+}
+
+func TestHierarchyBuildCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ch, err := buildHierarchy(ctx, seedGraph().snapshot())
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, ch)
+}
+
+func TestHierarchyReloadsSupersedeBuilds(t *testing.T) {
+	g := seedGraph()
+	built := make(chan struct{}, 16)
+	g.EnableContractionHierarchies(func(time.Duration) {
+		// onBuilt runs without the graph lock, so it may query the graph
+		_ = g.HierarchyReady()
+		built <- struct{}{}
+	})
+	<-built
+
+	// each load cancels the build started by the one before it
+	for i := 1; i <= 10; i++ {
+		assert.NoError(t, g.LoadEdges([]string{fmt.Sprintf("AB%d", i), "BC1"}))
+	}
+	select {
+	case <-built:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hierarchy was not built")
+	}
+	dist, _, stats := g.ShortestPathWith("A", "C", AlgorithmCH)
+	assert.Equal(t, AlgorithmCH, stats.Algorithm)
+	assert.Equal(t, 11, dist)
+
+	g.mutex.RLock()
+	assert.Nil(t, g.chCancel)
+	g.mutex.RUnlock()
+}
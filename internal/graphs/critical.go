@@ -0,0 +1,335 @@
+package graphs
+
+import (
+	"sort"
+)
+
+// CriticalEdge is an edge whose closure disconnects the listed town pairs.
+type CriticalEdge struct {
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+	Disconnects [][2]string `json:"disconnects"`
+}
+
+// CriticalTown is a town whose closure disconnects the listed town pairs.
+type CriticalTown struct {
+	Town        string      `json:"town"`
+	Disconnects [][2]string `json:"disconnects"`
+}
+
+// CriticalReport lists the critical edges and towns of the network.
+type CriticalReport struct {
+	View   string         `json:"view"`
+	Origin string         `json:"origin,omitempty"`
+	Edges  []CriticalEdge `json:"edges"`
+	Towns  []CriticalTown `json:"towns"`
+}
+
+// CriticalFrom reports, for trips leaving origin, every edge and town that
+// all routes to some destination must use. Removing one disconnects origin
+// from those destinations. It is computed from the dominator tree of the
+// graph in which every edge is subdivided by an extra node, so edges and
+// towns are handled by the same pass. It returns ErrNoSuchRoute for an
+// unknown origin.
+func (g *Graph) CriticalFrom(origin string) (CriticalReport, error) {
+	report := CriticalReport{View: "directed", Origin: origin, Edges: []CriticalEdge{}, Towns: []CriticalTown{}}
+	c := g.snapshot()
+	names := c.names
+	start, ok := c.id(origin)
+	if !ok {
+		return CriticalReport{}, ErrNoSuchRoute
+	}
+
+	// towns are 0..n-1, the edge in slot k becomes node n+k with u->n+k->w
+	n := len(names)
+	succ := make([][]int, n+len(c.targets))
+	edgeEnds := make([][2]int, len(c.targets))
+	for u := 0; u < n; u++ {
+		lo, hi := c.out(u)
+		for k := lo; k < hi; k++ {
+			edgeEnds[k] = [2]int{u, int(c.targets[k])}
+			succ[u] = append(succ[u], n+k)
+			succ[n+k] = []int{int(c.targets[k])}
+		}
+	}
+	idom := dominators(succ, start)
+
+	towns := make(map[int][]int)
+	edges := make(map[int][]int)
+	for v := 0; v < n; v++ {
+		if v == start || idom[v] < 0 {
+			continue
+		}
+		for d := idom[v]; d != start; d = idom[d] {
+			if d < n {
+				towns[d] = append(towns[d], v)
+			} else {
+				edges[d] = append(edges[d], v)
+			}
+		}
+	}
+
+	pairs := func(vs []int) [][2]string {
+		out := make([][2]string, 0, len(vs))
+		for _, v := range vs {
+			out = append(out, [2]string{origin, names[v]})
+		}
+		sortPairs(out)
+		return out
+	}
+	for d, vs := range towns {
+		report.Towns = append(report.Towns, CriticalTown{Town: names[d], Disconnects: pairs(vs)})
+	}
+	for d, vs := range edges {
+		ends := edgeEnds[d-n]
+		report.Edges = append(report.Edges, CriticalEdge{From: names[ends[0]], To: names[ends[1]], Disconnects: pairs(vs)})
+	}
+	sortCritical(&report)
+	return report, nil
+}
+
+// dominators returns the immediate dominator of every node reachable from
+// start (start is its own), or -1 for unreachable nodes, using the iterative
+// Cooper-Harvey-Kennedy algorithm.
+func dominators(succ [][]int, start int) []int {
+	n := len(succ)
+	post := make([]int, n)
+	for i := range post {
+		post[i] = -1
+	}
+	var order []int
+	seen := make([]bool, n)
+	var dfs func(v int)
+	dfs = func(v int) {
+		seen[v] = true
+		for _, w := range succ[v] {
+			if !seen[w] {
+				dfs(w)
+			}
+		}
+		post[v] = len(order)
+		order = append(order, v)
+	}
+	dfs(start)
+
+	pred := make([][]int, n)
+	for v := range succ {
+		if !seen[v] {
+			continue
+		}
+		for _, w := range succ[v] {
+			pred[w] = append(pred[w], v)
+		}
+	}
+
+	idom := make([]int, n)
+	for i := range idom {
+		idom[i] = -1
+	}
+	idom[start] = start
+	intersect := func(a, b int) int {
+		for a != b {
+			for post[a] < post[b] {
+				a = idom[a]
+			}
+			for post[b] < post[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(order) - 1; i >= 0; i-- {
+			v := order[i]
+			if v == start {
+				continue
+			}
+			next := -1
+			for _, p := range pred[v] {
+				if idom[p] < 0 {
+					continue
+				}
+				if next < 0 {
+					next = p
+				} else {
+					next = intersect(p, next)
+				}
+			}
+			if next != idom[v] {
+				idom[v] = next
+				changed = true
+			}
+		}
+	}
+	return idom
+}
+
+// CriticalUndirected treats every track as usable in both directions and
+// reports the bridges and articulation towns of that view, with the town
+// pairs each one separates. A pair of opposite edges counts as two tracks.
+// One depth-first search finds them all: closing one splits its component
+// into subtrees of the search tree and the rest, so the pairs come from
+// ranges of the visiting order without searching again.
+func (g *Graph) CriticalUndirected() CriticalReport {
+	report := CriticalReport{View: "undirected", Edges: []CriticalEdge{}, Towns: []CriticalTown{}}
+	c := g.snapshot()
+	names := c.names
+	n := len(names)
+
+	type track struct{ u, w int }
+	var tracks []track
+	adj := make([][]int, n)
+	for u := 0; u < n; u++ {
+		lo, hi := c.out(u)
+		for i := lo; i < hi; i++ {
+			w := int(c.targets[i])
+			adj[u] = append(adj[u], len(tracks))
+			adj[w] = append(adj[w], len(tracks))
+			tracks = append(tracks, track{u, w})
+		}
+	}
+	other := func(t, v int) int {
+		if tracks[t].u == v {
+			return tracks[t].w
+		}
+		return tracks[t].u
+	}
+
+	// the subtree of v is order[disc[v] : disc[v]+size[v]], and the
+	// component of v is the subtree of root[v]
+	disc := make([]int, n)
+	low := make([]int, n)
+	size := make([]int, n)
+	root := make([]int, n)
+	for i := range disc {
+		disc[i] = -1
+	}
+	var order []int
+	// bridges holds each bridge with the child town below it; cut holds the
+	// children of a town whose subtrees only reach the rest through it
+	type bridge struct{ track, child int }
+	var bridges []bridge
+	cut := make(map[int][]int)
+	var dfs func(v, via int)
+	dfs = func(v, via int) {
+		disc[v] = len(order)
+		low[v] = disc[v]
+		order = append(order, v)
+		for _, t := range adj[v] {
+			if t == via {
+				continue
+			}
+			w := other(t, v)
+			if disc[w] >= 0 {
+				low[v] = min(low[v], disc[w])
+				continue
+			}
+			root[w] = root[v]
+			dfs(w, t)
+			low[v] = min(low[v], low[w])
+			if low[w] > disc[v] {
+				bridges = append(bridges, bridge{t, w})
+			}
+			if low[w] >= disc[v] {
+				cut[v] = append(cut[v], w)
+			}
+		}
+		size[v] = len(order) - disc[v]
+	}
+	for v := 0; v < n; v++ {
+		if disc[v] < 0 {
+			root[v] = v
+			dfs(v, -1)
+		}
+	}
+
+	subtree := func(v int) []int { return order[disc[v] : disc[v]+size[v]] }
+	// rest lists the component of v without v's subtrees of the given
+	// children and, when skip is set, without v itself.
+	rest := func(v int, children []int, skip bool) []int {
+		var out []int
+		comp := subtree(root[v])
+		for i := 0; i < len(comp); {
+			switch u := comp[i]; {
+			case len(children) > 0 && u == children[0]:
+				i += size[u]
+				children = children[1:]
+			default:
+				if u != v || !skip {
+					out = append(out, u)
+				}
+				i++
+			}
+		}
+		return out
+	}
+	// separated lists the pairs of towns in different pieces.
+	separated := func(pieces [][]int) [][2]string {
+		var ids [][2]int
+		for i, p := range pieces {
+			for _, q := range pieces[i+1:] {
+				for _, a := range p {
+					for _, b := range q {
+						ids = append(ids, [2]int{min(a, b), max(a, b)})
+					}
+				}
+			}
+		}
+		// names are sorted, so ids sort the same way
+		sort.Slice(ids, func(i, j int) bool {
+			if ids[i][0] != ids[j][0] {
+				return ids[i][0] < ids[j][0]
+			}
+			return ids[i][1] < ids[j][1]
+		})
+		out := make([][2]string, len(ids))
+		for i, p := range ids {
+			out[i] = [2]string{names[p[0]], names[p[1]]}
+		}
+		return out
+	}
+
+	for _, b := range bridges {
+		below := subtree(b.child)
+		report.Edges = append(report.Edges, CriticalEdge{
+			From:        names[tracks[b.track].u],
+			To:          names[tracks[b.track].w],
+			Disconnects: separated([][]int{below, rest(b.child, []int{b.child}, false)}),
+		})
+	}
+	for v, children := range cut {
+		// the root of a search tree is only critical with several subtrees
+		if root[v] == v && len(children) < 2 {
+			continue
+		}
+		pieces := [][]int{rest(v, children, true)}
+		for _, w := range children {
+			pieces = append(pieces, subtree(w))
+		}
+		report.Towns = append(report.Towns, CriticalTown{Town: names[v], Disconnects: separated(pieces)})
+	}
+	sortCritical(&report)
+	return report
+}
+
+func sortPairs(pairs [][2]string) {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+}
+
+func sortCritical(r *CriticalReport) {
+	sort.Slice(r.Edges, func(i, j int) bool {
+		if r.Edges[i].From != r.Edges[j].From {
+			return r.Edges[i].From < r.Edges[j].From
+		}
+		return r.Edges[i].To < r.Edges[j].To
+	})
+	sort.Slice(r.Towns, func(i, j int) bool {
+		return r.Towns[i].Town < r.Towns[j].Town
+	})
+}
@@ -0,0 +1,121 @@
+package graphs
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCriticalFrom(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB1", "BC1", "CD1", "BD5"}))
+
+	r, err := g.CriticalFrom("A")
+	assert.NoError(t, err)
+	assert.Equal(t, []CriticalTown{
+		{Town: "B", Disconnects: [][2]string{{"A", "C"}, {"A", "D"}}},
+	}, r.Towns)
+	assert.Equal(t, []CriticalEdge{
+		{From: "A", To: "B", Disconnects: [][2]string{{"A", "B"}, {"A", "C"}, {"A", "D"}}},
+		{From: "B", To: "C", Disconnects: [][2]string{{"A", "C"}}},
+	}, r.Edges)
+
+	// every town in the seed graph is reachable from A in more than one way
+	r, err = seedGraph().CriticalFrom("A")
+	assert.NoError(t, err)
+	assert.Empty(t, r.Towns)
+	assert.Empty(t, r.Edges)
+
+	_, err = g.CriticalFrom("X")
+	assert.ErrorIs(t, err, ErrNoSuchRoute)
+}
+
+func TestCriticalUndirected(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB1", "BA1", "BC1", "CD1", "DB1", "DE1"}))
+
+	r := g.CriticalUndirected()
+	assert.Equal(t, []CriticalEdge{
+		{From: "D", To: "E", Disconnects: [][2]string{{"A", "E"}, {"B", "E"}, {"C", "E"}, {"D", "E"}}},
+	}, r.Edges)
+	assert.Equal(t, []CriticalTown{
+		{Town: "B", Disconnects: [][2]string{{"A", "C"}, {"A", "D"}, {"A", "E"}}},
+		{Town: "D", Disconnects: [][2]string{{"A", "E"}, {"B", "E"}, {"C", "E"}}},
+	}, r.Towns)
+}
+
+// criticalByClosing is CriticalUndirected computed the slow way, by closing
+// every track and town in turn and comparing reachability.
+func criticalByClosing(g *Graph) CriticalReport {
+	report := CriticalReport{View: "undirected", Edges: []CriticalEdge{}, Towns: []CriticalTown{}}
+	edges := g.EdgeList()
+	var towns []string
+	for _, e := range edges {
+		towns = append(towns, e.From, e.To)
+	}
+	slices.Sort(towns)
+	towns = slices.Compact(towns)
+	reach := func(skipEdge int, skipTown string) map[string]map[string]bool {
+		adj := map[string][]string{}
+		for i, e := range edges {
+			if i != skipEdge && e.From != skipTown && e.To != skipTown {
+				adj[e.From] = append(adj[e.From], e.To)
+				adj[e.To] = append(adj[e.To], e.From)
+			}
+		}
+		out := map[string]map[string]bool{}
+		for _, s := range towns {
+			seen := map[string]bool{s: true}
+			stack := []string{s}
+			for len(stack) > 0 {
+				v := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				for _, w := range adj[v] {
+					if !seen[w] {
+						seen[w] = true
+						stack = append(stack, w)
+					}
+				}
+			}
+			out[s] = seen
+		}
+		return out
+	}
+	split := func(after map[string]map[string]bool, skip string) [][2]string {
+		before := reach(-1, "")
+		var out [][2]string
+		for i, a := range towns {
+			for _, b := range towns[i+1:] {
+				if a != skip && b != skip && before[a][b] && !after[a][b] {
+					out = append(out, [2]string{a, b})
+				}
+			}
+		}
+		return out
+	}
+	for i, e := range edges {
+		if pairs := split(reach(i, ""), ""); len(pairs) > 0 {
+			report.Edges = append(report.Edges, CriticalEdge{From: e.From, To: e.To, Disconnects: pairs})
+		}
+	}
+	for _, v := range towns {
+		if pairs := split(reach(-1, v), v); len(pairs) > 0 {
+			report.Towns = append(report.Towns, CriticalTown{Town: v, Disconnects: pairs})
+		}
+	}
+	sortCritical(&report)
+	return report
+}
+
+func TestCriticalUndirectedMatchesClosing(t *testing.T) {
+	for _, edges := range [][]string{
+		{"AB1", "BC1", "CA1", "CD1", "DE1", "EF1", "FD1", "FG1", "HI1"},
+		{"AB1", "AC1", "AD1", "BE1", "EB1", "CF1", "FG1", "GC1"},
+		{"AB1", "BC1", "CD1", "DE1"},
+	} {
+		g := NewGraph()
+		assert.NoError(t, g.LoadEdges(edges))
+		assert.Equal(t, criticalByClosing(g), g.CriticalUndirected(), edges)
+	}
+}
@@ -0,0 +1,171 @@
+package graphs
+
+import (
+	"sort"
+)
+
+// rawEdge is a validated edge as read from the input, before interning.
+type rawEdge struct {
+	from     string
+	to       string
+	distance int
+	capacity int
+}
+
+// csr is one immutable version of the graph in compressed sparse row form.
+// Towns are interned to dense ids in lexicographic order. The edges leaving
+// town v occupy slots offsets[v]..offsets[v+1] of the parallel edge arrays,
+// in the order they were loaded; the edges entering v are listed the same
+// way in the in* arrays.
+type csr struct {
+	names []string
+	ids   map[string]int
+
+	offsets  []int32
+	targets  []int32
+	dists    []int
+	caps     []int
+	inOffset []int32
+	inSource []int32
+	inDists  []int
+
+	// scale is how many distance steps make one unit, see DistanceScale.
+	scale int
+
+	// alt holds the landmark distances, set before the version is shared.
+	alt *landmarkSet
+}
+
+// newCSR interns the towns of edges and lays the edges out by source and by
+// destination. edges must already be validated.
+func newCSR(edges []rawEdge) *csr {
+	set := make(map[string]struct{})
+	for _, e := range edges {
+		set[e.from] = struct{}{}
+		set[e.to] = struct{}{}
+	}
+	c := &csr{names: make([]string, 0, len(set)), ids: make(map[string]int, len(set)), scale: 1}
+	for n := range set {
+		c.names = append(c.names, n)
+	}
+	sort.Strings(c.names)
+	for i, n := range c.names {
+		c.ids[n] = i
+	}
+
+	n, m := len(c.names), len(edges)
+	c.offsets = make([]int32, n+1)
+	c.inOffset = make([]int32, n+1)
+	for _, e := range edges {
+		c.offsets[c.ids[e.from]+1]++
+		c.inOffset[c.ids[e.to]+1]++
+	}
+	for v := 0; v < n; v++ {
+		c.offsets[v+1] += c.offsets[v]
+		c.inOffset[v+1] += c.inOffset[v]
+	}
+
+	c.targets = make([]int32, m)
+	c.dists = make([]int, m)
+	c.caps = make([]int, m)
+	c.inSource = make([]int32, m)
+	c.inDists = make([]int, m)
+	next := append([]int32{}, c.offsets[:n]...)
+	inNext := append([]int32{}, c.inOffset[:n]...)
+	for _, e := range edges {
+		u, w := c.ids[e.from], c.ids[e.to]
+		slot := next[u]
+		next[u]++
+		c.targets[slot] = int32(w)
+		c.dists[slot] = e.distance
+		c.caps[slot] = e.capacity
+		in := inNext[w]
+		inNext[w]++
+		c.inSource[in] = int32(u)
+		c.inDists[in] = e.distance
+	}
+	return c
+}
+
+// size returns the number of towns.
+func (c *csr) size() int {
+	return len(c.names)
+}
+
+// id returns the id of a town.
+func (c *csr) id(name string) (int, bool) {
+	v, ok := c.ids[name]
+	return v, ok
+}
+
+// out returns the slot range of the edges leaving v.
+func (c *csr) out(v int) (int, int) {
+	return int(c.offsets[v]), int(c.offsets[v+1])
+}
+
+// in returns the slot range, in the in* arrays, of the edges entering v.
+func (c *csr) in(v int) (int, int) {
+	return int(c.inOffset[v]), int(c.inOffset[v+1])
+}
+
+// find returns the slot of the edge u->w.
+func (c *csr) find(u, w int) (int, bool) {
+	lo, hi := c.out(u)
+	for i := lo; i < hi; i++ {
+		if int(c.targets[i]) == w {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// edge returns the public form of the edge in slot i.
+func (c *csr) edge(i int) Edge {
+	return Edge{To: c.names[c.targets[i]], Distance: c.dists[i], Capacity: c.caps[i]}
+}
+
+// capacity returns the capacity of slot i, falling back to DefaultCapacity.
+func (c *csr) capacity(i int) int {
+	if c.caps[i] > 0 {
+		return c.caps[i]
+	}
+	return DefaultCapacity
+}
+
+// towns maps ids back to names.
+func (c *csr) towns(ids []int) []string {
+	out := make([]string, len(ids))
+	for i, v := range ids {
+		out[i] = c.names[v]
+	}
+	return out
+}
+
+// adjacency rebuilds the string keyed adjacency list, with an entry for
+// every town that has outgoing edges.
+func (c *csr) adjacency() map[string][]Edge {
+	nodes := make(map[string][]Edge)
+	for v := range c.names {
+		lo, hi := c.out(v)
+		if lo == hi {
+			continue
+		}
+		edges := make([]Edge, 0, hi-lo)
+		for i := lo; i < hi; i++ {
+			edges = append(edges, c.edge(i))
+		}
+		nodes[c.names[v]] = edges
+	}
+	return nodes
+}
+
+// sources returns the number of towns with outgoing edges.
+func (c *csr) sources() int {
+	count := 0
+	for v := range c.names {
+		if c.offsets[v+1] > c.offsets[v] {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,128 @@
+package graphs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DisjointMode selects what two redundant routes must not share.
+type DisjointMode string
+
+const (
+	// EdgeDisjoint routes share no track but may pass through the same town.
+	EdgeDisjoint DisjointMode = "edge"
+	// NodeDisjoint routes share no intermediate town (and therefore no track).
+	NodeDisjoint DisjointMode = "node"
+)
+
+// ParseDisjointMode validates a mode name, defaulting to EdgeDisjoint when empty
+func ParseDisjointMode(s string) (DisjointMode, error) {
+	switch DisjointMode(s) {
+	case "", EdgeDisjoint:
+		return EdgeDisjoint, nil
+	case NodeDisjoint:
+		return NodeDisjoint, nil
+	}
+	return "", fmt.Errorf("invalid disjoint mode: %q", s)
+}
+
+// Route is a path through the graph together with its total distance.
+type Route struct {
+	Path     []string `json:"path"`
+	Distance int      `json:"distance"`
+}
+
+// DisjointPaths returns the pair of routes between two distinct towns with
+// the smallest combined distance that share no edge (or, in NodeDisjoint
+// mode, no intermediate town). It is Suurballe's algorithm expressed as a
+// two unit min-cost flow. ok is false when no such pair exists.
+func (g *Graph) DisjointPaths(from, to string, mode DisjointMode) ([]Route, bool) {
+	return g.DisjointPathsWithStats(from, to, mode, nil)
+}
+
+// DisjointPathsWithStats is DisjointPaths recording the work of the
+// shortest augmenting path searches in stats, which may be nil.
+func (g *Graph) DisjointPathsWithStats(from, to string, mode DisjointMode, stats *SearchStats) ([]Route, bool) {
+	defer stats.timed(time.Now())
+	if from == "" || to == "" || from == to {
+		return nil, false
+	}
+	c := g.snapshot()
+	s, okFrom := c.id(from)
+	t, okTo := c.id(to)
+	if !okFrom || !okTo {
+		return nil, false
+	}
+	names := c.names
+
+	// In node-disjoint mode every town v is split into in(v)=2v and
+	// out(v)=2v+1 joined by a unit arc, so only one route can pass through.
+	in := func(v int) int { return v }
+	out := func(v int) int { return v }
+	size := len(names)
+	if mode == NodeDisjoint {
+		in = func(v int) int { return 2 * v }
+		out = func(v int) int { return 2*v + 1 }
+		size = 2 * len(names)
+	}
+	net := newFlowNetwork(size)
+	if mode == NodeDisjoint {
+		for v := range names {
+			capacity := 1
+			if v == s || v == t {
+				capacity = 2
+			}
+			net.addArc(in(v), out(v), capacity, 0)
+		}
+	}
+	for u := range names {
+		lo, hi := c.out(u)
+		for i := lo; i < hi; i++ {
+			net.addArc(out(u), in(int(c.targets[i])), 1, c.dists[i])
+		}
+	}
+
+	if sent, _ := net.minCostFlow(out(s), in(t), 2, stats); sent < 2 {
+		return nil, false
+	}
+
+	// Decompose the flow into two routes by walking saturated arcs.
+	routes := make([]Route, 0, 2)
+	for k := 0; k < 2; k++ {
+		r := Route{Path: []string{from}}
+		for v := out(s); v != in(t); {
+			next := -1
+			for _, i := range net.adj[v] {
+				if i%2 == 0 && net.arcs[i].flow > 0 {
+					next = i
+					break
+				}
+			}
+			a := &net.arcs[next]
+			a.flow--
+			// Split arcs (in(v)->out(v)) carry no distance and add no town.
+			if mode == EdgeDisjoint || v%2 == 1 {
+				r.Path = append(r.Path, names[townOf(a.to, mode)])
+				r.Distance += a.cost
+			}
+			v = a.to
+		}
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Distance != routes[j].Distance {
+			return routes[i].Distance < routes[j].Distance
+		}
+		return strings.Join(routes[i].Path, "") < strings.Join(routes[j].Path, "")
+	})
+	return routes, true
+}
+
+func townOf(v int, mode DisjointMode) int {
+	if mode == NodeDisjoint {
+		return v / 2
+	}
+	return v
+}
@@ -0,0 +1,49 @@
+package graphs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisjointPathsSeed(t *testing.T) {
+	g := seedGraph()
+
+	for _, mode := range []DisjointMode{EdgeDisjoint, NodeDisjoint} {
+		routes, ok := g.DisjointPaths("A", "C", mode)
+		assert.True(t, ok)
+		assert.Equal(t, []Route{
+			{Path: []string{"A", "B", "C"}, Distance: 9},
+			{Path: []string{"A", "D", "C"}, Distance: 13},
+		}, routes)
+	}
+}
+
+func TestDisjointPathsAvoidsGreedyTrap(t *testing.T) {
+	g := NewGraph()
+	// the single shortest route S-A-B-T blocks every second route
+	assert.NoError(t, g.LoadEdges([]string{"SA1", "AB1", "BT1", "SB2", "AT2"}))
+
+	routes, ok := g.DisjointPaths("S", "T", EdgeDisjoint)
+	assert.True(t, ok)
+	assert.Equal(t, []Route{
+		{Path: []string{"S", "A", "T"}, Distance: 3},
+		{Path: []string{"S", "B", "T"}, Distance: 3},
+	}, routes)
+}
+
+func TestDisjointPathsNodeMode(t *testing.T) {
+	g := NewGraph()
+	// every route from A to B passes through C
+	assert.NoError(t, g.LoadEdges([]string{"AC1", "CB1", "AD1", "DC1", "CE1", "EB1"}))
+
+	routes, ok := g.DisjointPaths("A", "B", EdgeDisjoint)
+	assert.True(t, ok)
+	assert.Equal(t, 6, routes[0].Distance+routes[1].Distance)
+
+	_, ok = g.DisjointPaths("A", "B", NodeDisjoint)
+	assert.False(t, ok)
+
+	_, ok = g.DisjointPaths("A", "A", EdgeDisjoint)
+	assert.False(t, ok)
+}
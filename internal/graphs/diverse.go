@@ -0,0 +1,189 @@
+package graphs
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDiverseCount is the number of routes asked for by default.
+	DefaultDiverseCount = 3
+	// MaxDiverseCount bounds the routes returned by DiverseRoutes.
+	MaxDiverseCount = 10
+	// DefaultMaxOverlap is the default bound on the shared distance of two
+	// diverse routes, as a fraction of the shorter one.
+	DefaultMaxOverlap = 0.5
+	// DefaultPenalty multiplies the weight of every edge of a route found.
+	DefaultPenalty = 1.4
+	// diverseRounds is how many penalised searches are run per route asked
+	// for before giving up.
+	diverseRounds = 8
+	// penaltyScale keeps penalised weights integral.
+	penaltyScale = 1024
+)
+
+// DiverseOptions tunes DiverseRoutes. Zero values select the defaults.
+type DiverseOptions struct {
+	Count      int
+	MaxOverlap float64
+	Penalty    float64
+	// Stats, if not nil, receives the work of all penalised searches.
+	Stats *SearchStats
+}
+
+// DiverseRoute is one of a set of alternative routes. Overlap is the
+// largest fraction of distance it shares with any other route of the set,
+// relative to the shorter of the two.
+type DiverseRoute struct {
+	Path     []string `json:"path"`
+	Distance int      `json:"distance"`
+	Overlap  float64  `json:"overlap"`
+}
+
+// DiverseRoutes returns up to opts.Count routes between two distinct towns
+// whose pairwise shared distance is below opts.MaxOverlap of the shorter
+// route, using the penalty method: Dijkstra is rerun with the weight of
+// every edge already used multiplied by opts.Penalty, and each new route is
+// kept only if it differs enough from the ones kept so far. The shortest
+// route always comes first. It returns ErrNoSuchRoute when the towns are not
+// connected.
+func (g *Graph) DiverseRoutes(from, to string, opts DiverseOptions) ([]DiverseRoute, error) {
+	defer opts.Stats.timed(time.Now())
+	if opts.Count == 0 {
+		opts.Count = DefaultDiverseCount
+	}
+	if opts.MaxOverlap == 0 {
+		opts.MaxOverlap = DefaultMaxOverlap
+	}
+	if opts.Penalty == 0 {
+		opts.Penalty = DefaultPenalty
+	}
+	switch {
+	case opts.Count < 1 || opts.Count > MaxDiverseCount:
+		return nil, fmt.Errorf("count must be between 1 and %d", MaxDiverseCount)
+	case opts.MaxOverlap < 0 || opts.MaxOverlap > 1:
+		return nil, fmt.Errorf("maxOverlap must be between 0 and 1")
+	case opts.Penalty <= 1:
+		return nil, fmt.Errorf("penalty must be greater than 1")
+	case from == to:
+		return nil, fmt.Errorf("from and to must be different towns")
+	}
+	c := g.snapshot()
+	src, okFrom := c.id(from)
+	dst, okTo := c.id(to)
+	if !okFrom || !okTo {
+		return nil, ErrNoSuchRoute
+	}
+
+	weight := make([]int, len(c.dists))
+	for i, d := range c.dists {
+		weight[i] = d * penaltyScale
+	}
+	// kept[k] holds the edge slots of route k
+	var kept [][]int
+	for round := 0; round < opts.Count*diverseRounds && len(kept) < opts.Count; round++ {
+		slots := weightedPath(c, src, dst, weight, opts.Stats)
+		if slots == nil {
+			break
+		}
+		ok := true
+		for _, other := range kept {
+			if overlap(c, slots, other) >= opts.MaxOverlap {
+				ok = false
+				break
+			}
+		}
+		if ok || len(kept) == 0 {
+			kept = append(kept, slots)
+		}
+		for _, i := range slots {
+			weight[i] = int(math.Ceil(float64(weight[i]) * opts.Penalty))
+		}
+	}
+	if len(kept) == 0 {
+		return nil, ErrNoSuchRoute
+	}
+
+	routes := make([]DiverseRoute, len(kept))
+	for k, slots := range kept {
+		r := DiverseRoute{Path: []string{from}}
+		for _, i := range slots {
+			r.Path = append(r.Path, c.names[c.targets[i]])
+			r.Distance += c.dists[i]
+		}
+		for j, other := range kept {
+			if j != k {
+				r.Overlap = math.Max(r.Overlap, overlap(c, slots, other))
+			}
+		}
+		routes[k] = r
+	}
+	sort.SliceStable(routes[1:], func(i, j int) bool {
+		a, b := routes[1+i], routes[1+j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		return strings.Join(a.Path, "") < strings.Join(b.Path, "")
+	})
+	return routes, nil
+}
+
+// overlap returns the distance shared by two routes, given as edge slots,
+// as a fraction of the shorter one.
+func overlap(c *csr, a, b []int) float64 {
+	inB := make(map[int]bool, len(b))
+	lenB := 0
+	for _, i := range b {
+		inB[i] = true
+		lenB += c.dists[i]
+	}
+	shared, lenA := 0, 0
+	for _, i := range a {
+		lenA += c.dists[i]
+		if inB[i] {
+			shared += c.dists[i]
+		}
+	}
+	return float64(shared) / float64(min(lenA, lenB))
+}
+
+// weightedPath runs Dijkstra from one town to another under the given slot
+// weights and returns the edge slots of the route, or nil.
+func weightedPath(c *csr, from, to int, weight []int, stats *SearchStats) []int {
+	settled := make([]bool, c.size())
+	parent := make([]int32, c.size())
+	pq := &priorityQueue{}
+	heap.Push(pq, pqItem{node: int32(from), parent: -1})
+	stats.push()
+	for pq.Len() > 0 {
+		curr := heap.Pop(pq).(pqItem)
+		if settled[curr.node] {
+			continue
+		}
+		settled[curr.node] = true
+		parent[curr.node] = curr.parent
+		stats.expand()
+		if int(curr.node) == to {
+			path := pathTo(parent, from, to)
+			slots := make([]int, len(path)-1)
+			for k := range slots {
+				slots[k], _ = c.find(path[k], path[k+1])
+			}
+			return slots
+		}
+		lo, hi := c.out(int(curr.node))
+		for i := lo; i < hi; i++ {
+			if settled[c.targets[i]] {
+				continue
+			}
+			stats.relax()
+			heap.Push(pq, pqItem{node: c.targets[i], dist: curr.dist + weight[i], parent: curr.node})
+			stats.push()
+		}
+	}
+	return nil
+}
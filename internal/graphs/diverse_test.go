@@ -0,0 +1,58 @@
+package graphs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiverseRoutes(t *testing.T) {
+	g := seedGraph()
+
+	routes, err := g.DiverseRoutes("A", "C", DiverseOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, routes, 3)
+	assert.Equal(t, DiverseRoute{Path: []string{"A", "B", "C"}, Distance: 9, Overlap: 4.0 / 9}, routes[0])
+	assert.Equal(t, DiverseRoute{Path: []string{"A", "D", "C"}, Distance: 13, Overlap: 0}, routes[1])
+	assert.Equal(t, []string{"A", "E", "B", "C"}, routes[2].Path)
+	for _, r := range routes {
+		assert.Less(t, r.Overlap, DefaultMaxOverlap)
+	}
+
+	// a stricter bound drops the route sharing B->C
+	routes, err = g.DiverseRoutes("A", "C", DiverseOptions{MaxOverlap: 0.1})
+	assert.NoError(t, err)
+	assert.Len(t, routes, 2)
+
+	routes, err = g.DiverseRoutes("A", "C", DiverseOptions{Count: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []DiverseRoute{{Path: []string{"A", "B", "C"}, Distance: 9}}, routes)
+}
+
+func TestDiverseRoutesDense(t *testing.T) {
+	g := denseGraph(t)
+	routes, err := g.DiverseRoutes("A", "Z", DiverseOptions{Count: 5, MaxOverlap: 0.3})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, routes)
+	shortest, _ := g.ShortestPath("A", "Z")
+	assert.Equal(t, shortest, routes[0].Distance)
+	for _, r := range routes {
+		assert.Less(t, r.Overlap, 0.3)
+	}
+}
+
+func TestDiverseRoutesErrors(t *testing.T) {
+	g := seedGraph()
+	_, err := g.DiverseRoutes("A", "A", DiverseOptions{})
+	assert.Error(t, err)
+	_, err = g.DiverseRoutes("A", "C", DiverseOptions{Count: MaxDiverseCount + 1})
+	assert.Error(t, err)
+	_, err = g.DiverseRoutes("A", "C", DiverseOptions{MaxOverlap: 1.5})
+	assert.Error(t, err)
+	_, err = g.DiverseRoutes("A", "C", DiverseOptions{Penalty: 0.5})
+	assert.Error(t, err)
+	_, err = g.DiverseRoutes("C", "A", DiverseOptions{})
+	assert.ErrorIs(t, err, ErrNoSuchRoute)
+	_, err = g.DiverseRoutes("A", "X", DiverseOptions{})
+	assert.ErrorIs(t, err, ErrNoSuchRoute)
+}
@@ -0,0 +1,328 @@
+package graphs
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// dotToken is a lexical token of the DOT language. Quoted strings keep
+// their quotes so they are never mistaken for keywords or operators.
+type dotToken struct {
+	text   string
+	line   int
+	column int
+}
+
+func (t dotToken) value() string {
+	if len(t.text) >= 2 && t.text[0] == '"' {
+		return strings.ReplaceAll(t.text[1:len(t.text)-1], `\"`, `"`)
+	}
+	if len(t.text) >= 2 && t.text[0] == '<' {
+		return t.text[1 : len(t.text)-1]
+	}
+	return t.text
+}
+
+// lexDOT splits DOT source into tokens, dropping comments and preprocessor
+// lines.
+func lexDOT(src string) ([]dotToken, error) {
+	var tokens []dotToken
+	line, lineStart := 1, 0
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+		switch {
+		case c == '\n':
+			line++
+			i++
+			lineStart = i
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '#' && (i == 0 || src[i-1] == '\n'):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("dot line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			if nl := strings.LastIndexByte(src[i:i+2+end], '\n'); nl >= 0 {
+				lineStart = i + nl + 1
+			}
+			i += end + 4
+			continue
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			i += 2
+		case strings.ContainsRune("{}[]=;,:", rune(c)):
+			i++
+		case c == '"':
+			for i++; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("dot line %d: unterminated string", line)
+			}
+			i++
+		case c == '<':
+			for depth := 0; i < len(src); i++ {
+				if src[i] == '<' {
+					depth++
+				} else if src[i] == '>' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("dot line %d: unterminated html string", line)
+			}
+			i++
+		case c == '-' || c == '.' || c == '_' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			for i++; i < len(src); i++ {
+				d := src[i]
+				if !(d == '.' || d == '_' || d >= 0x80 || unicode.IsLetter(rune(d)) || unicode.IsDigit(rune(d))) {
+					break
+				}
+			}
+		default:
+			return nil, fmt.Errorf("dot line %d: unexpected character %q", line, c)
+		}
+		tok := dotToken{text: src[start:i], line: line, column: utf8.RuneCountInString(src[lineStart:start]) + 1}
+		if nl := strings.LastIndexByte(tok.text, '\n'); nl >= 0 {
+			line += strings.Count(tok.text, "\n")
+			lineStart = start + nl + 1
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+type dotParser struct {
+	tokens   []dotToken
+	pos      int
+	directed bool
+	specs    []EdgeSpec
+}
+
+func (p *dotParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *dotParser) next() dotToken {
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *dotParser) errorf(format string, args ...interface{}) error {
+	line := 0
+	if p.pos < len(p.tokens) {
+		line = p.tokens[p.pos].line
+	} else if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return fmt.Errorf("dot line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *dotParser) expect(text string) error {
+	if p.peek() != text {
+		return p.errorf("expected %q, found %q", text, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+func isDOTKeyword(text, keyword string) bool {
+	return strings.EqualFold(text, keyword)
+}
+
+// isDOTID reports whether a token can name a node or attribute.
+func isDOTID(text string) bool {
+	return text != "" && !strings.ContainsAny(text[:1], "{}[]=;,:") && text != "->" && text != "--"
+}
+
+// importDOT reads the edges of a Graphviz graph or digraph. Distances come
+// from a distance, weight or numeric label attribute and capacities from a
+// capacity attribute. Edges of an undirected graph are loaded in both
+// directions; node, graph and subgraph attributes are ignored.
+func importDOT(r io.Reader) ([]EdgeSpec, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := lexDOT(string(src))
+	if err != nil {
+		return nil, err
+	}
+	p := &dotParser{tokens: tokens}
+	if isDOTKeyword(p.peek(), "strict") {
+		p.pos++
+	}
+	switch {
+	case isDOTKeyword(p.peek(), "digraph"):
+		p.directed = true
+	case isDOTKeyword(p.peek(), "graph"):
+	default:
+		return nil, p.errorf("expected graph or digraph, found %q", p.peek())
+	}
+	p.pos++
+	if p.peek() != "{" && isDOTID(p.peek()) {
+		p.pos++
+	}
+	if err := p.block(); err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, p.errorf("unexpected %q after graph", p.peek())
+	}
+	return p.specs, nil
+}
+
+// block parses `{ stmt_list }`.
+func (p *dotParser) block() error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for p.peek() != "}" {
+		if p.pos >= len(p.tokens) {
+			return p.errorf("unterminated graph body")
+		}
+		if err := p.statement(); err != nil {
+			return err
+		}
+		if p.peek() == ";" || p.peek() == "," {
+			p.pos++
+		}
+	}
+	p.pos++
+	return nil
+}
+
+func (p *dotParser) statement() error {
+	tok := p.peek()
+	switch {
+	case tok == "{":
+		return p.block()
+	case isDOTKeyword(tok, "subgraph"):
+		p.pos++
+		if p.peek() != "{" && isDOTID(p.peek()) {
+			p.pos++
+		}
+		return p.block()
+	case isDOTKeyword(tok, "graph") || isDOTKeyword(tok, "node") || isDOTKeyword(tok, "edge"):
+		p.pos++
+		_, err := p.attributes()
+		return err
+	case !isDOTID(tok):
+		return p.errorf("unexpected %q", tok)
+	}
+
+	first := p.tokens[p.pos]
+	nodes := []string{p.nodeID()}
+	if p.peek() == "=" {
+		// graph attribute
+		p.pos++
+		if !isDOTID(p.peek()) {
+			return p.errorf("expected attribute value, found %q", p.peek())
+		}
+		p.pos++
+		return nil
+	}
+	var ops []string
+	for p.peek() == "->" || p.peek() == "--" {
+		op := p.next().text
+		if (op == "->") != p.directed {
+			return p.errorf("%s not allowed in this kind of graph", op)
+		}
+		if !isDOTID(p.peek()) {
+			return p.errorf("expected node after %s, found %q", op, p.peek())
+		}
+		ops = append(ops, op)
+		nodes = append(nodes, p.nodeID())
+	}
+	attrs, err := p.attributes()
+	if err != nil || len(ops) == 0 {
+		return err
+	}
+	spec := EdgeSpec{}
+	distance := attrs["distance"]
+	if distance == "" {
+		distance = attrs["weight"]
+	}
+	if distance == "" {
+		distance = attrs["label"]
+	}
+	if spec.Distance, spec.Scale, err = parseDistance(distance); err != nil {
+		return fmt.Errorf("dot line %d: edge %s has no numeric distance, weight or label", first.line, strings.Join(nodes, " "+ops[0]+" "))
+	}
+	if c, ok := attrs["capacity"]; ok {
+		if spec.Capacity, err = strconv.Atoi(c); err != nil {
+			return fmt.Errorf("dot line %d: invalid capacity %q", first.line, c)
+		}
+	}
+	for k := 1; k < len(nodes); k++ {
+		spec.From, spec.To = nodes[k-1], nodes[k]
+		spec.Source = Source{Line: first.line, Column: first.column, Text: strings.Join(nodes, " "+ops[0]+" ")}
+		p.specs = append(p.specs, spec)
+		if !p.directed {
+			p.specs = append(p.specs, EdgeSpec{From: spec.To, To: spec.From, Distance: spec.Distance, Scale: spec.Scale, Capacity: spec.Capacity})
+		}
+	}
+	return nil
+}
+
+// nodeID consumes a node id and skips any port.
+func (p *dotParser) nodeID() string {
+	id := p.next().value()
+	for p.peek() == ":" && p.pos+1 < len(p.tokens) && isDOTID(p.tokens[p.pos+1].text) {
+		p.pos += 2
+	}
+	return id
+}
+
+// attributes parses zero or more `[ a=b, ... ]` lists.
+func (p *dotParser) attributes() (map[string]string, error) {
+	attrs := map[string]string{}
+	for p.peek() == "[" {
+		p.pos++
+		for p.peek() != "]" {
+			if !isDOTID(p.peek()) {
+				return nil, p.errorf("expected attribute name, found %q", p.peek())
+			}
+			name := strings.ToLower(p.next().value())
+			value := "true"
+			if p.peek() == "=" {
+				p.pos++
+				if !isDOTID(p.peek()) {
+					return nil, p.errorf("expected value for %s, found %q", name, p.peek())
+				}
+				value = p.next().value()
+			}
+			attrs[name] = strings.TrimSpace(value)
+			if p.peek() == "," || p.peek() == ";" {
+				p.pos++
+			}
+		}
+		p.pos++
+	}
+	return attrs, nil
+}
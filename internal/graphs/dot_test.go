@@ -0,0 +1,51 @@
+package graphs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportDOT(t *testing.T) {
+	src := `// the seed network
+digraph "rails" {
+  rankdir=LR;
+  node [shape=box]
+  A -> B [label=5]
+  B -> C [weight=4, capacity=2];
+  /* a chain shares its attributes */
+  C -> D -> "E" [distance="8"]
+  subgraph cluster_0 { E:n -> A [label=7] }
+}`
+	g := NewGraph()
+	assert.NoError(t, g.Import(strings.NewReader(src), FormatDOT))
+	assert.Equal(t, map[string][]Edge{
+		"A": {{To: "B", Distance: 5}},
+		"B": {{To: "C", Distance: 4, Capacity: 2}},
+		"C": {{To: "D", Distance: 8}},
+		"D": {{To: "E", Distance: 8}},
+		"E": {{To: "A", Distance: 7}},
+	}, g.Nodes())
+
+	// undirected graphs are loaded both ways
+	assert.NoError(t, g.Import(strings.NewReader(`strict graph { A -- B [label=3] }`), FormatDOT))
+	assert.Equal(t, map[string][]Edge{
+		"A": {{To: "B", Distance: 3}},
+		"B": {{To: "A", Distance: 3}},
+	}, g.Nodes())
+}
+
+func TestImportDOTErrors(t *testing.T) {
+	g := NewGraph()
+	cases := map[string]string{
+		"digraph { A -> B }":             "dot line 1: edge A -> B has no numeric distance, weight or label",
+		"digraph {\n A -- B [label=1] }": `dot line 2: -- not allowed in this kind of graph`,
+		"digraph { A -> B [label=1]":     "dot line 1: unterminated graph body",
+		"tree { }":                       `dot line 1: expected graph or digraph, found "tree"`,
+		"digraph { A -> \"B }":           "dot line 1: unterminated string",
+	}
+	for src, msg := range cases {
+		assert.EqualError(t, g.Import(strings.NewReader(src), FormatDOT), msg, src)
+	}
+}
@@ -0,0 +1,117 @@
+package graphs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Problems found with individual edges while loading a graph. They are
+// wrapped in a ParseError giving the offending input.
+var (
+	ErrInvalidToken     = errors.New("invalid edge token")
+	ErrInvalidTown      = errors.New("invalid town name")
+	ErrSelfLoop         = errors.New("self-loop not allowed")
+	ErrInvalidDistance  = errors.New("invalid distance")
+	ErrInvalidCapacity  = errors.New("invalid capacity")
+	ErrDuplicateEdge    = errors.New("duplicate edge")
+	ErrInvalidDirective = errors.New("invalid directive")
+)
+
+// Source locates an edge in the input it was read from. Line and Column
+// count from 1 and are zero when the format has no positions. File is set
+// for edges read from a file included by the input.
+type Source struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+	Text   string `json:"text"`
+}
+
+func (s Source) position() string {
+	if s.Line == 0 {
+		return ""
+	}
+	pos := fmt.Sprintf("line %d, column %d", s.Line, s.Column)
+	if s.File != "" {
+		pos = s.File + " " + pos
+	}
+	return pos
+}
+
+// ParseError is a problem with one edge of the input. Err is one of the
+// Err* values above, possibly wrapped with more detail.
+type ParseError struct {
+	Source
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%q: %v", e.Text, e.Err)
+	if pos := e.position(); pos != "" {
+		msg = pos + ": " + msg
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+func (e *ParseError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Source
+		Error string `json:"error"`
+	}{e.Source, e.Err.Error()})
+}
+
+// ParseErrors lists every problem found in one load, sorted by position.
+type ParseErrors []*ParseError
+
+func (es ParseErrors) Error() string {
+	if len(es) == 1 {
+		return es[0].Error()
+	}
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(es), strings.Join(msgs, "; "))
+}
+
+func (es ParseErrors) Unwrap() []error {
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = e
+	}
+	return errs
+}
+
+// add records a problem with the input at src.
+func (es *ParseErrors) add(src Source, err error) {
+	*es = append(*es, &ParseError{Source: src, Err: err})
+}
+
+// err returns the errors sorted by position, or nil when there are none.
+// Errors in the input come first, then those in included files by name.
+// Errors without a position follow, those found reading the input before
+// those found validating it.
+func (es ParseErrors) err() error {
+	if len(es) == 0 {
+		return nil
+	}
+	sort.SliceStable(es, func(i, j int) bool {
+		a, b := es[i].Source, es[j].Source
+		if (a.Line == 0) != (b.Line == 0) {
+			return b.Line == 0
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return es
+}
@@ -0,0 +1,88 @@
+package graphs
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadReportsEveryError(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB5"}))
+
+	input := "AB5, BC4, A5\nCC3, AB6,\n  XY0, BD1/0\n"
+	err := g.Import(strings.NewReader(input), FormatText)
+	var errs ParseErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, []Source{
+		{Line: 1, Column: 11, Text: "A5"},
+		{Line: 2, Column: 1, Text: "CC3"},
+		{Line: 2, Column: 6, Text: "AB6"},
+		{Line: 3, Column: 3, Text: "XY0"},
+		{Line: 3, Column: 8, Text: "BD1/0"},
+	}, []Source{errs[0].Source, errs[1].Source, errs[2].Source, errs[3].Source, errs[4].Source})
+	assert.ErrorIs(t, errs[0], ErrInvalidToken)
+	assert.ErrorIs(t, errs[1], ErrSelfLoop)
+	assert.ErrorIs(t, errs[2], ErrDuplicateEdge)
+	assert.ErrorIs(t, errs[3], ErrInvalidDistance)
+	assert.ErrorIs(t, errs[4], ErrInvalidCapacity)
+	assert.ErrorIs(t, err, ErrDuplicateEdge)
+	assert.EqualError(t, errs[2], `line 2, column 6: "AB6": duplicate edge A->B, first at line 1, column 1`)
+	assert.True(t, strings.HasPrefix(err.Error(), `5 errors: line 1, column 11: "A5": invalid edge token; `))
+
+	// nothing was loaded
+	assert.Equal(t, map[string][]Edge{"A": {{To: "B", Distance: 5}}}, g.Nodes())
+
+	body, _ := json.Marshal(errs[:2])
+	assert.JSONEq(t, `[
+		{"line":1,"column":11,"text":"A5","error":"invalid edge token"},
+		{"line":2,"column":1,"text":"CC3","error":"self-loop not allowed"}
+	]`, string(body))
+}
+
+func TestLoadEdgesErrorsWithoutPositions(t *testing.T) {
+	g := NewGraph()
+	err := g.LoadEdges([]string{"AB5", "AB5", "A5"})
+	var errs ParseErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	// tokens without positions are read before they are validated
+	assert.EqualError(t, errs[0], `"A5": invalid edge token`)
+	assert.EqualError(t, errs[1], `"AB5": duplicate edge A->B`)
+}
+
+func TestImportErrorPositions(t *testing.T) {
+	g := NewGraph()
+
+	err := g.Import(strings.NewReader("[\n  \"AB5\",\n  {\"from\":\"B\",\"to\":\"B\",\"distance\":1},\n  \"AB5\"\n]"), FormatJSON)
+	assert.EqualError(t, err, `2 errors: line 3, column 3: "{\"from\":\"B\",\"to\":\"B\",\"distance\":1}": self-loop not allowed; `+
+		`line 4, column 3: "AB5": duplicate edge A->B, first at line 2, column 3`)
+
+	// objects that do not parse carry their JSON too
+	err = g.Import(strings.NewReader(`[{"from":"A","to":"B","distance":2.5001}, {"from":"B","to":1}]`), FormatJSON)
+	var errs ParseErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.ErrorIs(t, errs[0], ErrInvalidDistance)
+	assert.Equal(t, Source{Line: 1, Column: 2, Text: `{"from":"A","to":"B","distance":2.5001}`}, errs[0].Source)
+	assert.ErrorIs(t, errs[1], ErrInvalidToken)
+	assert.Equal(t, Source{Line: 1, Column: 43, Text: `{"from":"B","to":1}`}, errs[1].Source)
+
+	err = g.Import(strings.NewReader("digraph {\n  A -> B [label=1]\n  /* x */ B -> A -> B [label=2]\n}"), FormatDOT)
+	assert.EqualError(t, err, `line 3, column 11: "B -> A -> B": duplicate edge A->B, first at line 2, column 3`)
+
+	err = g.Import(strings.NewReader("A,B,x\nC,C,1\nD,E,1,y\n"), FormatCSV)
+	errs = nil
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+	assert.ErrorIs(t, errs[0], ErrInvalidDistance)
+	assert.ErrorIs(t, errs[1], ErrSelfLoop)
+	assert.Equal(t, Source{Line: 3, Column: 7, Text: "y"}, errs[2].Source)
+
+	// graph-level problems are still plain errors
+	err = g.LoadGraphFromFile("../../graph123.txt")
+	assert.False(t, errors.As(err, &errs))
+}
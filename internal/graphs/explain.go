@@ -0,0 +1,90 @@
+package graphs
+
+import (
+	"time"
+)
+
+// Reasons a branch of a search can be pruned, as reported in
+// SearchStats.Pruned.
+const (
+	PruneMaxStops      = "maxStops"
+	PruneMaxDistance   = "maxDistance"
+	PruneDistinctNodes = "distinctNodes"
+	// PruneLandmarks counts partial routes the landmark lower bound proved
+	// could not reach the destination within the distance budget.
+	PruneLandmarks = "landmarks"
+	// PruneBottleneck counts edges skipped for breaking the bottleneck bound.
+	PruneBottleneck = "bottleneck"
+)
+
+// SearchStats describes the work a search performed. For the depth-first
+// route enumerations HeapPushes counts pushes onto the DFS stack.
+type SearchStats struct {
+	// Algorithm is the algorithm that actually ran.
+	Algorithm     Algorithm `json:"algorithm,omitempty"`
+	NodesExpanded int       `json:"nodesExpanded"`
+	EdgesRelaxed  int       `json:"edgesRelaxed"`
+	HeapPushes    int       `json:"heapPushes"`
+	// Pruned counts discarded branches keyed by the constraint that cut them.
+	Pruned   map[string]int `json:"pruned,omitempty"`
+	WallTime time.Duration  `json:"wallTimeNs"`
+}
+
+// The recording methods are no-ops on a nil receiver so algorithms can be
+// instrumented unconditionally.
+
+// expand records a settled node.
+func (s *SearchStats) expand() {
+	if s != nil {
+		s.NodesExpanded++
+	}
+}
+
+// relax records an edge being examined.
+func (s *SearchStats) relax() {
+	if s != nil {
+		s.EdgesRelaxed++
+	}
+}
+
+// push records an entry added to the queue or stack.
+func (s *SearchStats) push() {
+	if s != nil {
+		s.HeapPushes++
+	}
+}
+
+// prune records a branch discarded for the given reason.
+func (s *SearchStats) prune(reason string) {
+	if s == nil {
+		return
+	}
+	if s.Pruned == nil {
+		s.Pruned = make(map[string]int)
+	}
+	s.Pruned[reason]++
+}
+
+// add accumulates the counters of o, used to merge parallel branches.
+func (s *SearchStats) add(o SearchStats) {
+	if s == nil {
+		return
+	}
+	s.NodesExpanded += o.NodesExpanded
+	s.EdgesRelaxed += o.EdgesRelaxed
+	s.HeapPushes += o.HeapPushes
+	for reason, n := range o.Pruned {
+		if s.Pruned == nil {
+			s.Pruned = make(map[string]int)
+		}
+		s.Pruned[reason] += n
+	}
+}
+
+// timed records the time elapsed since start; use as
+// defer stats.timed(time.Now()).
+func (s *SearchStats) timed(start time.Time) {
+	if s != nil {
+		s.WallTime = time.Since(start)
+	}
+}
@@ -0,0 +1,89 @@
+package graphs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aashi1008/hamburg-rails/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainShortestPath(t *testing.T) {
+	_, _, stats := seedGraph().ShortestPathWith("A", "C", AlgorithmDijkstra)
+	assert.Equal(t, AlgorithmDijkstra, stats.Algorithm)
+	assert.Equal(t, 5, stats.NodesExpanded)
+	assert.Equal(t, 7, stats.EdgesRelaxed)
+	assert.Equal(t, 8, stats.HeapPushes)
+	assert.Positive(t, stats.WallTime)
+}
+
+func TestExplainEnumerationPruning(t *testing.T) {
+	g := seedGraph()
+	ctx := context.Background()
+
+	stats := &SearchStats{}
+	count, err := g.CountTripsByStopsContext(ctx, "C", "C", 1, 3, EnumOptions{Stats: stats})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, map[string]int{PruneMaxStops: 6}, stats.Pruned)
+	assert.Equal(t, stats.HeapPushes, stats.NodesExpanded+stats.Pruned[PruneMaxStops])
+
+	stats = &SearchStats{}
+	req := models.RouteSearchRequest{Constraints: models.RouteSearchConstraints{MaxStops: 4, DistinctNodes: true}}
+	_, err = g.SearchRoutesContext(ctx, "A", "C", req, EnumOptions{Stats: stats})
+	assert.NoError(t, err)
+	assert.Positive(t, stats.Pruned[PruneDistinctNodes])
+	assert.Zero(t, stats.Pruned[PruneMaxDistance])
+	// every relaxed edge is either pushed or pruned
+	pruned := 0
+	for _, n := range stats.Pruned {
+		pruned += n
+	}
+	assert.Equal(t, stats.EdgesRelaxed, stats.HeapPushes+pruned)
+}
+
+func TestExplainParallelMatchesSequential(t *testing.T) {
+	g := denseGraph(t)
+	ctx := context.Background()
+	req := models.RouteSearchRequest{Constraints: models.RouteSearchConstraints{MaxDistance: 30}}
+
+	var seq, par SearchStats
+	_, err := g.SearchRoutesContext(ctx, "A", "Z", req, EnumOptions{Stats: &seq})
+	assert.NoError(t, err)
+	_, err = g.SearchRoutesContext(ctx, "A", "Z", req, EnumOptions{Workers: 4, Stats: &par})
+	assert.NoError(t, err)
+	seq.WallTime, par.WallTime = 0, 0
+	assert.Equal(t, seq, par)
+	assert.Positive(t, seq.Pruned[PruneLandmarks])
+}
+
+func TestExplainOtherQueries(t *testing.T) {
+	g := seedGraph()
+
+	stats := &SearchStats{}
+	_, _, _, ok := g.BottleneckPathWithStats("A", "C", Minimax, stats)
+	assert.True(t, ok)
+	assert.Positive(t, stats.NodesExpanded)
+	assert.Positive(t, stats.Pruned[PruneBottleneck])
+
+	stats = &SearchStats{}
+	_, ok = g.DisjointPathsWithStats("A", "C", EdgeDisjoint, stats)
+	assert.True(t, ok)
+	assert.Positive(t, stats.HeapPushes)
+
+	stats = &SearchStats{}
+	_, ok = g.ShortestPathTreeWithStats("A", false, stats)
+	assert.True(t, ok)
+	assert.Equal(t, 5, stats.NodesExpanded)
+
+	stats = &SearchStats{}
+	_, err := g.PlanTour("A", []string{"C", "E"}, TourOptions{Stats: stats})
+	assert.NoError(t, err)
+	assert.Equal(t, AlgorithmDijkstra, stats.Algorithm)
+	assert.Positive(t, stats.EdgesRelaxed)
+
+	stats = &SearchStats{}
+	_, err = g.DistanceWithStats([]string{"A", "B", "C"}, stats)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.EdgesRelaxed)
+}
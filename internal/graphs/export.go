@@ -0,0 +1,361 @@
+package graphs
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Export formats registered by this package, besides the import formats
+// that can also be written.
+const (
+	FormatGeoJSON = "geojson"
+	FormatMermaid = "mermaid"
+)
+
+// Exporter writes the edges of a graph, in load order grouped by source
+// town, along with any town coordinates.
+type Exporter interface {
+	Export(w io.Writer, edges []EdgeSpec, coords map[string]Coordinate) error
+}
+
+// ExporterFunc adapts a function to the Exporter interface.
+type ExporterFunc func(w io.Writer, edges []EdgeSpec, coords map[string]Coordinate) error
+
+func (f ExporterFunc) Export(w io.Writer, edges []EdgeSpec, coords map[string]Coordinate) error {
+	return f(w, edges, coords)
+}
+
+// MetadataExporter is implemented by exporters that can also write what the
+// input of the graph declared about it, such as its name and units.
+type MetadataExporter interface {
+	Exporter
+	ExportWithMetadata(w io.Writer, edges []EdgeSpec, coords map[string]Coordinate, meta Metadata) error
+}
+
+type exporterEntry struct {
+	exporter  Exporter
+	mediaType string
+}
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = map[string]exporterEntry{}
+)
+
+func init() {
+	RegisterExporter(FormatText, textExporter{}, "text/plain")
+	RegisterExporter(FormatCSV, ExporterFunc(exportCSV), "text/csv")
+	RegisterExporter(FormatDOT, ExporterFunc(exportDOT), "text/vnd.graphviz")
+	RegisterExporter(FormatGraphML, ExporterFunc(exportGraphML), "application/graphml+xml")
+	RegisterExporter(FormatGeoJSON, ExporterFunc(exportGeoJSON), "application/geo+json")
+	RegisterExporter(FormatMermaid, ExporterFunc(exportMermaid), "text/vnd.mermaid")
+}
+
+// RegisterExporter makes exp available as format, written with the given
+// media type. Registering a format again replaces it.
+func RegisterExporter(format string, exp Exporter, mediaType string) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	exporters[format] = exporterEntry{exporter: exp, mediaType: mediaType}
+}
+
+// ExportMediaType returns the media type of a registered export format.
+func ExportMediaType(format string) (string, bool) {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	e, ok := exporters[format]
+	return e.mediaType, ok
+}
+
+// ExportFormats returns the names of the registered export formats.
+func ExportFormats() []string {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	formats := make([]string, 0, len(exporters))
+	for f := range exporters {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// NegotiateExportFormat picks the export format for an Accept header,
+// preferring higher quality values and, among equal ones, the earlier media
+// range. It returns "" when no registered format is listed by its exact
+// media type, so that wildcards keep the caller's default.
+func NegotiateExportFormat(accept string) string {
+	type choice struct {
+		format string
+		q      float64
+	}
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	var choices []choice
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		for format, e := range exporters {
+			if e.mediaType == mediaType && q > 0 {
+				choices = append(choices, choice{format, q})
+			}
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	if len(choices) == 0 {
+		return ""
+	}
+	return choices[0].format
+}
+
+// EdgeList returns the edges of the current graph grouped by source town in
+// lexicographic order, each group in load order. Scale is set when the
+// distances have decimals.
+func (g *Graph) EdgeList() []EdgeSpec {
+	c := g.snapshot()
+	edges := make([]EdgeSpec, 0, len(c.targets))
+	scale := 0
+	if c.scale > 1 {
+		scale = c.scale
+	}
+	for v := range c.names {
+		lo, hi := c.out(v)
+		for i := lo; i < hi; i++ {
+			edges = append(edges, EdgeSpec{From: c.names[v], To: c.names[c.targets[i]], Distance: c.dists[i], Scale: scale, Capacity: c.caps[i]})
+		}
+	}
+	return edges
+}
+
+// Export writes the current graph and town coordinates in the given format.
+func (g *Graph) Export(w io.Writer, format string) error {
+	exportersMu.RLock()
+	e, ok := exporters[format]
+	exportersMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unsupported graph format: %q", format)
+	}
+	if me, ok := e.exporter.(MetadataExporter); ok {
+		return me.ExportWithMetadata(w, g.EdgeList(), g.Coordinates(), g.Metadata())
+	}
+	return e.exporter.Export(w, g.EdgeList(), g.Coordinates())
+}
+
+// token is the text form of an edge, `AB5` or `AB5/12`. Town names are
+// concatenated, so edges that would read back between different towns,
+// such as BERLIN->HAMBURG, are written with an arrow, `BERLIN->HAMBURG:290`.
+func (e EdgeSpec) token() string {
+	suffix := e.distanceText()
+	if e.Capacity > 0 {
+		suffix += "/" + strconv.Itoa(e.Capacity)
+	}
+	t := e.From + e.To + suffix
+	if parsed, errs := parseTokens([]Source{{Text: t}}); len(errs) > 0 || parsed[0].From != e.From || parsed[0].To != e.To {
+		t = e.From + "->" + e.To + ":" + suffix
+	}
+	return t
+}
+
+// textExporter writes the original `AB5` tokens, after a header with the
+// @name, @version and @units the graph was loaded with.
+type textExporter struct{}
+
+func (textExporter) Export(w io.Writer, edges []EdgeSpec, coords map[string]Coordinate) error {
+	return textExporter{}.ExportWithMetadata(w, edges, coords, Metadata{})
+}
+
+func (textExporter) ExportWithMetadata(w io.Writer, edges []EdgeSpec, _ map[string]Coordinate, meta Metadata) error {
+	var b strings.Builder
+	for _, d := range []struct{ name, value string }{{"@name", meta.Name}, {"@version", meta.Version}, {"@units", meta.Units}} {
+		if d.value != "" {
+			fmt.Fprintf(&b, "%s %s\n", d.name, d.value)
+		}
+	}
+	tokens := make([]string, len(edges))
+	for i, e := range edges {
+		tokens[i] = e.token()
+	}
+	b.WriteString(strings.Join(tokens, ", ") + "\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func exportCSV(w io.Writer, edges []EdgeSpec, _ map[string]Coordinate) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"from", "to", "distance", "capacity"})
+	for _, e := range edges {
+		capacity := ""
+		if e.Capacity > 0 {
+			capacity = strconv.Itoa(e.Capacity)
+		}
+		_ = cw.Write([]string{e.From, e.To, e.distanceText(), capacity})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportDOT writes a digraph with the distance as edge label. Town names
+// are quoted since DOT keywords are case-insensitive.
+func exportDOT(w io.Writer, edges []EdgeSpec, coords map[string]Coordinate) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph rails {")
+	for _, town := range edgeTowns(edges) {
+		if c, ok := coords[town]; ok {
+			fmt.Fprintf(bw, "  %q [pos=\"%g,%g\"];\n", town, c.Lon, c.Lat)
+		}
+	}
+	for _, e := range edges {
+		fmt.Fprintf(bw, "  %q -> %q [label=%s", e.From, e.To, e.distanceText())
+		if e.Capacity > 0 {
+			fmt.Fprintf(bw, ", capacity=%d", e.Capacity)
+		}
+		fmt.Fprintln(bw, "];")
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func exportGraphML(w io.Writer, edges []EdgeSpec, coords map[string]Coordinate) error {
+	distanceType := "int"
+	for _, e := range edges {
+		if e.Scale > 1 {
+			distanceType = "double"
+			break
+		}
+	}
+	doc := graphmlDoc{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphmlKey{
+			{ID: "distance", For: "edge", Name: "distance", Type: distanceType},
+			{ID: "capacity", For: "edge", Name: "capacity", Type: "int"},
+		},
+	}
+	if len(coords) > 0 {
+		doc.Keys = append(doc.Keys,
+			graphmlKey{ID: "lat", For: "node", Name: "lat", Type: "double"},
+			graphmlKey{ID: "lon", For: "node", Name: "lon", Type: "double"})
+	}
+	gr := graphmlGraph{ID: "rails", EdgeDefault: "directed"}
+	for _, town := range edgeTowns(edges) {
+		n := graphmlNode{ID: town}
+		if c, ok := coords[town]; ok {
+			n.Data = []graphmlData{
+				{Key: "lat", Value: strconv.FormatFloat(c.Lat, 'g', -1, 64)},
+				{Key: "lon", Value: strconv.FormatFloat(c.Lon, 'g', -1, 64)},
+			}
+		}
+		gr.Nodes = append(gr.Nodes, n)
+	}
+	for _, e := range edges {
+		ge := graphmlEdge{Source: e.From, Target: e.To, Data: []graphmlData{{Key: "distance", Value: e.distanceText()}}}
+		if e.Capacity > 0 {
+			ge.Data = append(ge.Data, graphmlData{Key: "capacity", Value: strconv.Itoa(e.Capacity)})
+		}
+		gr.Edges = append(gr.Edges, ge)
+	}
+	doc.Graphs = []graphmlGraph{gr}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type geoJSONFeature struct {
+	Type     string                 `json:"type"`
+	Geometry geoJSONGeometry        `json:"geometry"`
+	Props    map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// exportGeoJSON writes a FeatureCollection with a Point per town and a
+// LineString per edge. Towns without coordinates, and edges touching them,
+// are left out; it fails when no town has coordinates.
+func exportGeoJSON(w io.Writer, edges []EdgeSpec, coords map[string]Coordinate) error {
+	features := []geoJSONFeature{}
+	position := func(c Coordinate) []float64 { return []float64{c.Lon, c.Lat} }
+	for _, town := range edgeTowns(edges) {
+		if c, ok := coords[town]; ok {
+			features = append(features, geoJSONFeature{
+				Type:     "Feature",
+				Geometry: geoJSONGeometry{Type: "Point", Coordinates: position(c)},
+				Props:    map[string]interface{}{"town": town},
+			})
+		}
+	}
+	if len(features) == 0 {
+		return fmt.Errorf("geojson needs town coordinates, none are loaded for this graph")
+	}
+	for _, e := range edges {
+		from, okFrom := coords[e.From]
+		to, okTo := coords[e.To]
+		if !okFrom || !okTo {
+			continue
+		}
+		props := map[string]interface{}{"from": e.From, "to": e.To, "distance": json.Number(e.distanceText())}
+		if e.Capacity > 0 {
+			props["capacity"] = e.Capacity
+		}
+		features = append(features, geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geoJSONGeometry{Type: "LineString", Coordinates: [][]float64{position(from), position(to)}},
+			Props:    props,
+		})
+	}
+	return json.NewEncoder(w).Encode(map[string]interface{}{"type": "FeatureCollection", "features": features})
+}
+
+// exportMermaid writes a left-to-right flowchart labelled with distances.
+func exportMermaid(w io.Writer, edges []EdgeSpec, _ map[string]Coordinate) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "graph LR")
+	for _, e := range edges {
+		label := e.distanceText()
+		if e.Capacity > 0 {
+			label += "/" + strconv.Itoa(e.Capacity)
+		}
+		fmt.Fprintf(bw, "  %s -->|%s| %s\n", e.From, label, e.To)
+	}
+	return bw.Flush()
+}
+
+// edgeTowns returns the towns of edges in lexicographic order.
+func edgeTowns(edges []EdgeSpec) []string {
+	seen := map[string]bool{}
+	var towns []string
+	for _, e := range edges {
+		for _, t := range []string{e.From, e.To} {
+			if !seen[t] {
+				seen[t] = true
+				towns = append(towns, t)
+			}
+		}
+	}
+	sort.Strings(towns)
+	return towns
+}
@@ -0,0 +1,120 @@
+package graphs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportRoundTrip(t *testing.T) {
+	g := seedGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB5/3", "BC4", "CD8", "DC8", "DE6", "AD5", "CE2", "EB3", "AE7"}))
+	for _, format := range []string{FormatText, FormatCSV, FormatDOT, FormatGraphML} {
+		var buf bytes.Buffer
+		assert.NoError(t, g.Export(&buf, format), format)
+		copied := NewGraph()
+		assert.NoError(t, copied.Import(&buf, format), format)
+		assert.Equal(t, g.Nodes(), copied.Nodes(), format)
+	}
+}
+
+func TestExportText(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"BC4", "AB5/3", "AD5"}))
+	var buf bytes.Buffer
+	assert.NoError(t, g.Export(&buf, FormatText))
+	assert.Equal(t, "AB5/3, AD5, BC4\n", buf.String())
+
+	// BERLIN+HAMBURG would read back as BERLINHAMBUR->G
+	assert.NoError(t, g.LoadEdgeSpecs([]EdgeSpec{{From: "BERLIN", To: "HAMBURG", Distance: 290}}))
+	buf.Reset()
+	assert.NoError(t, g.Export(&buf, FormatText))
+	assert.Equal(t, "BERLIN->HAMBURG:290\n", buf.String())
+	buf.Reset()
+	assert.NoError(t, g.Export(&buf, FormatCSV))
+	assert.Equal(t, "from,to,distance,capacity\nBERLIN,HAMBURG,290,\n", buf.String())
+}
+
+func TestExportMermaid(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB5/3", "BC4"}))
+	var buf bytes.Buffer
+	assert.NoError(t, g.Export(&buf, FormatMermaid))
+	assert.Equal(t, "graph LR\n  A -->|5/3| B\n  B -->|4| C\n", buf.String())
+}
+
+func TestExportGeoJSON(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB5", "BC4"}))
+	var buf bytes.Buffer
+	assert.Error(t, g.Export(&buf, FormatGeoJSON))
+
+	assert.NoError(t, g.SetCoordinates(map[string]Coordinate{"A": {Lat: 53.55, Lon: 9.99}, "B": {Lat: 53.6, Lon: 10.05}}))
+	assert.NoError(t, g.Export(&buf, FormatGeoJSON))
+	var doc struct {
+		Type     string
+		Features []struct {
+			Geometry struct {
+				Type        string
+				Coordinates json.RawMessage
+			}
+			Properties map[string]interface{}
+		}
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "FeatureCollection", doc.Type)
+	// two towns and the one edge between towns with coordinates
+	assert.Len(t, doc.Features, 3)
+	assert.Equal(t, "Point", doc.Features[0].Geometry.Type)
+	assert.JSONEq(t, "[9.99,53.55]", string(doc.Features[0].Geometry.Coordinates))
+	assert.Equal(t, "LineString", doc.Features[2].Geometry.Type)
+	assert.Equal(t, map[string]interface{}{"from": "A", "to": "B", "distance": 5.0}, doc.Features[2].Properties)
+}
+
+func TestExportDOTCoordinates(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB5"}))
+	assert.NoError(t, g.SetCoordinates(map[string]Coordinate{"A": {Lat: 53.55, Lon: 9.99}}))
+	var buf bytes.Buffer
+	assert.NoError(t, g.Export(&buf, FormatDOT))
+	assert.Equal(t, "digraph rails {\n  \"A\" [pos=\"9.99,53.55\"];\n  \"A\" -> \"B\" [label=5];\n}\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, g.Export(&buf, FormatGraphML))
+	assert.Contains(t, buf.String(), `<data key="lat">53.55</data>`)
+	assert.True(t, strings.HasPrefix(buf.String(), `<?xml`))
+}
+
+func TestNegotiateExportFormat(t *testing.T) {
+	assert.Equal(t, "", NegotiateExportFormat(""))
+	assert.Equal(t, "", NegotiateExportFormat("*/*"))
+	assert.Equal(t, "", NegotiateExportFormat("application/json"))
+	assert.Equal(t, FormatDOT, NegotiateExportFormat("text/vnd.graphviz"))
+	assert.Equal(t, FormatGraphML, NegotiateExportFormat("text/plain;q=0.5, application/graphml+xml"))
+	assert.Equal(t, FormatText, NegotiateExportFormat("text/plain, text/csv"))
+	assert.Equal(t, FormatCSV, NegotiateExportFormat("text/plain;q=0, text/csv;q=0.1"))
+	_, ok := ExportMediaType(FormatGeoJSON)
+	assert.True(t, ok)
+	assert.Contains(t, ExportFormats(), FormatMermaid)
+}
+
+func TestExportTextRoundTrip(t *testing.T) {
+	g := NewGraph()
+	input := "@name Hamburg S-Bahn\n@version 2024.1\n@units km\nALTONA->BERLINERTOR:12.5/4, A->BC:5, AB->C:3, AB1, HAMBURG->BERLIN:290\n"
+	_, err := g.ImportWithOptions(strings.NewReader(input), FormatText, ImportOptions{})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, g.Export(&buf, FormatText))
+	assert.Equal(t, "@name Hamburg S-Bahn\n@version 2024.1\n@units km\n"+
+		"A->BC:5, AB1, ABC3, ALTONA->BERLINERTOR:12.5/4, HAMBURG->BERLIN:290\n", buf.String())
+
+	again := NewGraph()
+	_, err = again.ImportWithOptions(&buf, FormatText, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, g.EdgeList(), again.EdgeList())
+	assert.Equal(t, g.Metadata(), again.Metadata())
+}
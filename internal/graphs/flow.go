@@ -0,0 +1,195 @@
+package graphs
+
+import (
+	"container/heap"
+	"math"
+)
+
+// flowArc is one direction of a residual arc. Arcs are stored in pairs so
+// the reverse of arc i is always i^1.
+type flowArc struct {
+	to   int
+	cap  int
+	cost int
+	flow int
+}
+
+// flowNetwork is an integer-indexed residual network used by the flow based
+// algorithms (disjoint routes, max-flow).
+type flowNetwork struct {
+	adj  [][]int
+	arcs []flowArc
+}
+
+func newFlowNetwork(n int) *flowNetwork {
+	return &flowNetwork{adj: make([][]int, n)}
+}
+
+// addArc adds an arc u->v and its zero capacity reverse, returning the index
+// of the forward arc.
+func (f *flowNetwork) addArc(u, v, capacity, cost int) int {
+	idx := len(f.arcs)
+	f.arcs = append(f.arcs, flowArc{to: v, cap: capacity, cost: cost}, flowArc{to: u, cap: 0, cost: -cost})
+	f.adj[u] = append(f.adj[u], idx)
+	f.adj[v] = append(f.adj[v], idx+1)
+	return idx
+}
+
+func (f *flowNetwork) residual(i int) int {
+	return f.arcs[i].cap - f.arcs[i].flow
+}
+
+func (f *flowNetwork) push(i, amount int) {
+	f.arcs[i].flow += amount
+	f.arcs[i^1].flow -= amount
+}
+
+// minCostFlow sends up to k units from s to t along successive shortest
+// augmenting paths, using Dijkstra on reduced costs. All arc costs must be
+// non-negative. It returns the flow sent and its total cost.
+func (f *flowNetwork) minCostFlow(s, t, k int, stats *SearchStats) (int, int) {
+	n := len(f.adj)
+	potential := make([]int, n)
+	sent, cost := 0, 0
+	for sent < k {
+		dist := make([]int, n)
+		via := make([]int, n)
+		for i := range dist {
+			dist[i] = math.MaxInt
+			via[i] = -1
+		}
+		dist[s] = 0
+		pq := &flowQueue{}
+		heap.Push(pq, flowItem{node: s})
+		stats.push()
+		for pq.Len() > 0 {
+			curr := heap.Pop(pq).(flowItem)
+			if curr.dist > dist[curr.node] {
+				continue
+			}
+			stats.expand()
+			for _, i := range f.adj[curr.node] {
+				if f.residual(i) <= 0 {
+					continue
+				}
+				stats.relax()
+				a := f.arcs[i]
+				nd := curr.dist + a.cost + potential[curr.node] - potential[a.to]
+				if nd < dist[a.to] {
+					dist[a.to] = nd
+					via[a.to] = i
+					heap.Push(pq, flowItem{node: a.to, dist: nd})
+					stats.push()
+				}
+			}
+		}
+		if dist[t] == math.MaxInt {
+			break
+		}
+		for v := range potential {
+			if dist[v] != math.MaxInt {
+				potential[v] += dist[v]
+			}
+		}
+		amount := k - sent
+		for v := t; v != s; v = f.arcs[via[v]^1].to {
+			amount = min(amount, f.residual(via[v]))
+		}
+		for v := t; v != s; v = f.arcs[via[v]^1].to {
+			f.push(via[v], amount)
+			cost += amount * f.arcs[via[v]].cost
+		}
+		sent += amount
+	}
+	return sent, cost
+}
+
+type flowItem struct {
+	node int
+	dist int
+}
+
+type flowQueue []flowItem
+
+func (q flowQueue) Len() int            { return len(q) }
+func (q flowQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q flowQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *flowQueue) Push(x interface{}) { *q = append(*q, x.(flowItem)) }
+func (q *flowQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
+
+// maxFlow computes a maximum s-t flow with Dinic's algorithm and returns its value.
+func (f *flowNetwork) maxFlow(s, t int) int {
+	n := len(f.adj)
+	total := 0
+	for {
+		level := make([]int, n)
+		for i := range level {
+			level[i] = -1
+		}
+		level[s] = 0
+		queue := []int{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			for _, i := range f.adj[v] {
+				if f.residual(i) > 0 && level[f.arcs[i].to] < 0 {
+					level[f.arcs[i].to] = level[v] + 1
+					queue = append(queue, f.arcs[i].to)
+				}
+			}
+		}
+		if level[t] < 0 {
+			return total
+		}
+		next := make([]int, n)
+		var augment func(v, limit int) int
+		augment = func(v, limit int) int {
+			if v == t {
+				return limit
+			}
+			for ; next[v] < len(f.adj[v]); next[v]++ {
+				i := f.adj[v][next[v]]
+				a := f.arcs[i]
+				if f.residual(i) <= 0 || level[a.to] != level[v]+1 {
+					continue
+				}
+				if pushed := augment(a.to, min(limit, f.residual(i))); pushed > 0 {
+					f.push(i, pushed)
+					return pushed
+				}
+			}
+			return 0
+		}
+		for {
+			pushed := augment(s, math.MaxInt)
+			if pushed == 0 {
+				break
+			}
+			total += pushed
+		}
+	}
+}
+
+// reachable returns the nodes reachable from s through arcs with residual capacity.
+func (f *flowNetwork) reachable(s int) []bool {
+	seen := make([]bool, len(f.adj))
+	seen[s] = true
+	stack := []int{s}
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, i := range f.adj[v] {
+			if to := f.arcs[i].to; f.residual(i) > 0 && !seen[to] {
+				seen[to] = true
+				stack = append(stack, to)
+			}
+		}
+	}
+	return seen
+}
@@ -1,16 +1,19 @@
 package graphs
 
 import (
-	"bufio"
 	"container/heap"
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aashi1008/hamburg-rails/internal/models"
 )
@@ -18,229 +21,614 @@ import (
 type Edge struct {
 	To       string
 	Distance int
+	// Capacity is the number of trains per hour the track carries; zero
+	// means it was not specified, see DefaultCapacity.
+	Capacity int `json:",omitempty"`
 }
 
+// DefaultCapacity is the capacity assumed for edges loaded without one, so
+// that flow analysis on a plain network counts independent tracks.
+const DefaultCapacity = 1
+
+// ErrNoSuchRoute is returned when the requested towns are not connected.
+var ErrNoSuchRoute = errors.New("NO SUCH ROUTE")
+
 type Graph struct {
-	Nodes map[string][]Edge
 	mutex sync.RWMutex
+
+	// data is the current version of the graph. Loads replace it and never
+	// mutate it, so readers may keep using it after releasing the lock.
+	data *csr
+
+	// meta describes the current version, as declared by its input.
+	meta Metadata
+
+	// coords holds optional town positions used by the A* heuristic;
+	// kmSteps is the number of stored distance steps in a great-circle
+	// kilometre when every town has one and every edge is at least that
+	// long, and 0 when the heuristic is not admissible.
+	coords  map[string]Coordinate
+	kmSteps float64
+
+	// version increases on every load. When contraction hierarchies are
+	// enabled, ch holds the hierarchy for the current version once its
+	// background build finishes, and chBuilt is told how long it took.
+	// chCancel stops the build in progress, if any.
+	version   uint64
+	chEnabled bool
+	chBuilt   func(time.Duration)
+	ch        *contractionHierarchy
+	chCancel  context.CancelFunc
 }
 
 // NewGraph returns an empty graph
 func NewGraph() *Graph {
 	return &Graph{
-		Nodes: make(map[string][]Edge),
+		data: newCSR(nil),
 	}
 }
 
-// LoadGraphFromFile returns the graph data from file
+// Nodes returns the adjacency list of the current graph, keyed by the towns
+// that have outgoing edges. The map is built on every call, so callers may
+// keep and modify it. It replaces the former exported Nodes field, which
+// the graph no longer stores.
+func (g *Graph) Nodes() map[string][]Edge {
+	return g.snapshot().adjacency()
+}
+
+// NodeCount returns the number of towns that have outgoing edges
+func (g *Graph) NodeCount() int {
+	return g.snapshot().sources()
+}
+
+// LoadGraphFromFile returns the graph data from file. The format is picked
+// from the file extension, see ImportFormat, and defaults to the text format.
 func (g *Graph) LoadGraphFromFile(graphPath string) error {
+	_, err := g.ImportFile(graphPath, ImportOptions{})
+	return err
+}
+
+// ImportFile is LoadGraphFromFile with options, also returning the report of
+// the load. Text graph files may include files in their directory or below.
+func (g *Graph) ImportFile(graphPath string, opts ImportOptions) (LoadReport, error) {
 	file, err := os.Open(graphPath)
 	if err != nil {
-		return fmt.Errorf("error opening graph file: %v", err)
+		return LoadReport{}, fmt.Errorf("error opening graph file: %v", err)
 	}
 	defer file.Close()
-	scan := bufio.NewScanner(file)
-	var allEdges []string
-	for scan.Scan() {
-		text := scan.Text()
-		text = strings.TrimSpace(text)
-		if text == "" {
-			continue
-		}
-		paths := strings.Split(text, ",")
-		for _, path := range paths {
-			allEdges = append(allEdges, strings.TrimSpace(path))
-		}
+	format := ImportFormat(graphPath, "")
+	if format == "" {
+		format = FormatText
 	}
-
-	err = g.LoadEdges(allEdges)
+	opts.Includes, opts.Name = os.DirFS(filepath.Dir(graphPath)), filepath.Base(graphPath)
+	report, err := g.ImportWithOptions(file, format, opts)
 	if err != nil {
-		return fmt.Errorf("error opening graph file: %v", err)
+		return LoadReport{}, fmt.Errorf("error opening graph file: %w", err)
 	}
-	return nil
+	return report, nil
 }
 
-var tokenRegex = regexp.MustCompile(`^([A-Z]{1,16})([A-Z]{1,16})(\d+)$`)
+var tokenRegex = regexp.MustCompile(`^([A-Z]{1,16})([A-Z]{1,16})(\d+(?:\.\d+)?)(?:/(\d+))?$`)
+
+// arrowRegex matches the `A->B:5` form of a token, and `A<->B:5` for an
+// edge in both directions.
+var arrowRegex = regexp.MustCompile(`^([A-Z]{1,16})\s*(<?->)\s*([A-Z]{1,16})\s*:\s*(\d+(?:\.\d+)?)(?:/(\d+))?$`)
 
-// LoadEdges replaces the graph data
+// townRegex is the form every town name takes once upper-cased.
+var townRegex = regexp.MustCompile(`^[A-Z]{1,16}$`)
+
+// EdgeSpec is an edge as read by an importer, before validation.
+type EdgeSpec struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Distance int    `json:"distance"`
+	// Scale is how many steps of Distance make one unit, for distances
+	// with decimals; zero means 1.
+	Scale int `json:"-"`
+	// Capacity is zero when not given, see DefaultCapacity.
+	Capacity int `json:"capacity,omitempty"`
+	// Bidirectional adds the edge To->From as well.
+	Bidirectional bool `json:"bidirectional,omitempty"`
+	// Source locates the edge in the input for error messages.
+	Source Source `json:"-"`
+}
+
+// LoadEdges replaces the graph data. Tokens are `AB5`, or `AB5/12` to give
+// the edge a capacity of 12 trains per hour; distances may have up to
+// MaxDecimals decimal places, `AB2.5`. Towns may also be separated
+// by an arrow, `A->B:5`, and `A<->B:5` adds the edge in both directions.
+// It reports every invalid token at once as ParseErrors.
 func (g *Graph) LoadEdges(edges []string) error {
+	tokens := make([]Source, len(edges))
+	for i, e := range edges {
+		tokens[i] = Source{Text: e}
+	}
+	specs, errs := parseTokens(tokens)
+	_, err := g.loadSpecs(ImportResult{Edges: specs}, ImportOptions{}, errs)
+	return err
+}
 
-	newNodes := make(map[string][]Edge)
-	for _, e := range edges {
-		e = strings.ToUpper(strings.TrimSpace(e))
-		m := tokenRegex.FindStringSubmatch(e)
-		if m == nil {
-			return fmt.Errorf("invalid edge token: %q", e)
+// parseTokens splits the tokens described by LoadEdges into edges, skipping
+// the tokens it cannot read.
+func parseTokens(tokens []Source) ([]EdgeSpec, ParseErrors) {
+	specs := make([]EdgeSpec, 0, len(tokens))
+	var errs ParseErrors
+	for _, src := range tokens {
+		e := strings.ToUpper(strings.TrimSpace(src.Text))
+		var from, arrow, to, distance, capacityText string
+		if m := tokenRegex.FindStringSubmatch(e); m != nil {
+			from, to, distance, capacityText = m[1], m[2], m[3], m[4]
+		} else if m := arrowRegex.FindStringSubmatch(e); m != nil {
+			from, arrow, to, distance, capacityText = m[1], m[2], m[3], m[4], m[5]
+		} else {
+			errs.add(src, ErrInvalidToken)
+			continue
+		}
+		dist, scale, err := parseDistance(distance)
+		if err != nil {
+			errs.add(src, err)
+			continue
+		}
+		capacity := 0
+		if capacityText != "" {
+			capacity, err = strconv.Atoi(capacityText)
+			if err != nil || capacity <= 0 {
+				errs.add(src, ErrInvalidCapacity)
+				continue
+			}
 		}
-		from := m[1]
-		to := m[2]
-		if from == to {
-			return fmt.Errorf("self-loop not allowed: %s->%s", from, to)
+		specs = append(specs, EdgeSpec{From: from, To: to, Distance: dist, Scale: scale, Capacity: capacity, Bidirectional: arrow == "<->", Source: src})
+	}
+	return specs, errs
+}
+
+// LoadEdgeSpecs validates edges from any source and replaces the graph data
+// with them. Town names are upper-cased and must be 1 to 16 letters;
+// distances must be positive, capacities positive or zero, and there may be
+// no self-loops or duplicate edges. Every problem is reported at once as
+// ParseErrors, and the graph is left unchanged.
+func (g *Graph) LoadEdgeSpecs(specs []EdgeSpec) error {
+	_, err := g.loadSpecs(ImportResult{Edges: specs}, ImportOptions{}, nil)
+	return err
+}
+
+// loadSpecs validates what an importer read and loads it unless that or
+// reading the input found errors.
+func (g *Graph) loadSpecs(res ImportResult, opts ImportOptions, errs ParseErrors) (LoadReport, error) {
+	parsed, scale, invalid := validate(res.Edges)
+	err := append(errs, invalid...).err()
+	if err != nil && !opts.DryRun {
+		return LoadReport{}, err
+	}
+	if opts.Mirror {
+		parsed = mirrorEdges(parsed)
+	}
+	report := LoadReport{Warnings: res.Warnings}
+	report.Asymmetric, report.AsymmetricCount = asymmetricPairs(parsed, scale)
+	if opts.DryRun {
+		c := newCSR(parsed)
+		c.scale = scale
+		report.Lint, report.LintCount = lint(c)
+		return report, err
+	}
+	g.swap(parsed, scale, res.Metadata)
+	return report, nil
+}
+
+// validate checks specs and returns the valid edges, skipping duplicates,
+// with their distances in steps of 1/scale for the scale of the spec with
+// the most decimal places.
+func validate(specs []EdgeSpec) ([]rawEdge, int, ParseErrors) {
+	parsed := make([]rawEdge, 0, len(specs))
+	var errs ParseErrors
+	scale := 1
+	for _, e := range specs {
+		scale = max(scale, e.Scale)
+	}
+	seen := make(map[[2]string]Source, len(specs))
+	for _, e := range specs {
+		from := strings.ToUpper(strings.TrimSpace(e.From))
+		to := strings.ToUpper(strings.TrimSpace(e.To))
+		src := e.Source
+		if src.Text == "" {
+			arrow := "->"
+			if e.Bidirectional {
+				arrow = "<->"
+			}
+			src.Text = fmt.Sprintf("%s%s%s:%s", e.From, arrow, e.To, e.distanceText())
 		}
-		dist, err := strconv.Atoi(m[3])
-		if err != nil || dist <= 0 {
-			return fmt.Errorf("invalid distance for token %q", e)
+		switch {
+		case !townRegex.MatchString(from):
+			errs.add(src, fmt.Errorf("%w %q", ErrInvalidTown, from))
+			continue
+		case !townRegex.MatchString(to):
+			errs.add(src, fmt.Errorf("%w %q", ErrInvalidTown, to))
+			continue
+		case from == to:
+			errs.add(src, ErrSelfLoop)
+			continue
+		case e.Distance <= 0:
+			errs.add(src, fmt.Errorf("%w %s", ErrInvalidDistance, e.distanceText()))
+			continue
+		case e.Capacity < 0:
+			errs.add(src, fmt.Errorf("%w %d", ErrInvalidCapacity, e.Capacity))
+			continue
 		}
 
-		for _, edge := range newNodes[from] {
-			if edge.To == to {
-				return fmt.Errorf("duplicate edge: %s%s%d", from, to, dist)
+		pairs := [][2]string{{from, to}}
+		if e.Bidirectional {
+			pairs = append(pairs, [2]string{to, from})
+		}
+		for _, pair := range pairs {
+			if first, dup := seen[pair]; dup {
+				err := fmt.Errorf("%w %s->%s", ErrDuplicateEdge, pair[0], pair[1])
+				if pos := first.position(); pos != "" {
+					err = fmt.Errorf("%w, first at %s", err, pos)
+				}
+				errs.add(src, err)
+				continue
 			}
+			seen[pair] = src
+			parsed = append(parsed, rawEdge{from: pair[0], to: pair[1], distance: e.Distance * (scale / max(1, e.Scale)), capacity: e.Capacity})
 		}
-		newNodes[from] = append(newNodes[from], Edge{To: to, Distance: dist})
 	}
+	return parsed, scale, errs
+}
+
+// distanceText writes the distance of e as a decimal.
+func (e EdgeSpec) distanceText() string {
+	return formatDistance(e.Distance, e.Scale)
+}
+
+// swap makes a new version of the graph from validated edges current.
+func (g *Graph) swap(parsed []rawEdge, scale int, meta Metadata) {
+	data := newCSR(parsed)
+	data.scale = scale
+	data.alt = selectLandmarks(data, DefaultLandmarkCount)
 
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
-	g.Nodes = newNodes
-	return nil
+	g.data = data
+	g.meta = meta
+	g.kmSteps = heuristicSteps(g.data, g.coords, g.meta.Units)
+	g.startHierarchyBuild()
 }
 
-// snapshotNodes returns a shallow copy reference to the nodes map so
-// traversal can proceed without holding lock for the entire operation.
-func (g *Graph) snapshotNodes() map[string][]Edge {
+// Metadata returns what the input of the current version declared about it.
+func (g *Graph) Metadata() Metadata {
 	g.mutex.RLock()
 	defer g.mutex.RUnlock()
+	return g.meta
+}
 
-	snap := make(map[string][]Edge, len(g.Nodes))
-	for k, v := range g.Nodes {
-		snap[k] = v
-	}
-	return snap
+// snapshot returns the current version of the graph so traversal can
+// proceed without holding the lock for the entire operation.
+func (g *Graph) snapshot() *csr {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.data
 }
 
 // Distance calculates distance for a fixed path
 func (g *Graph) Distance(path []string) (int, error) {
-	snap := g.snapshotNodes()
+	return g.DistanceWithStats(path, nil)
+}
+
+// DistanceWithStats is Distance recording each town visited and each edge
+// looked up in stats, which may be nil.
+func (g *Graph) DistanceWithStats(path []string, stats *SearchStats) (int, error) {
+	defer stats.timed(time.Now())
+	c := g.snapshot()
 	total := 0
 	for i := 0; i < len(path)-1; i++ {
-		found := false
-		from := path[i]
-		to := path[i+1]
-		for _, e := range snap[from] {
-			if e.To == to {
-				total += e.Distance
-				found = true
-				break
-			}
+		stats.expand()
+		stats.relax()
+		from, okFrom := c.id(path[i])
+		to, okTo := c.id(path[i+1])
+		if !okFrom || !okTo {
+			return 0, ErrNoSuchRoute
 		}
+		slot, found := c.find(from, to)
 		if !found {
-			return 0, errors.New("NO SUCH ROUTE")
+			return 0, ErrNoSuchRoute
 		}
+		total += c.dists[slot]
 	}
 	return total, nil
 }
 
 // CountTripsByStops counts trips with stop constraints
 func (g *Graph) CountTripsByStops(from, to string, minStops, maxStops int) int {
+	count, _ := g.CountTripsByStopsContext(context.Background(), from, to, minStops, maxStops, EnumOptions{})
+	return count
+}
+
+// CountTripsByStopsContext is CountTripsByStops with the trips leaving from
+// through each of its edges counted as a separate branch, spread across
+// opts.Workers goroutines. It stops with ctx.Err() once ctx is done.
+func (g *Graph) CountTripsByStopsContext(ctx context.Context, from, to string, minStops, maxStops int, opts EnumOptions) (int, error) {
+	defer opts.Stats.timed(time.Now())
 	if maxStops < 0 || minStops < 0 {
-		return 0
+		return 0, nil
 	}
 	if minStops > maxStops {
-		return 0
+		return 0, nil
 	}
-	nodes := g.snapshotNodes()
-	count := 0
+	c := g.snapshot()
+	src, ok := c.id(from)
+	if !ok {
+		return 0, nil
+	}
+	dst, ok := c.id(to)
+	if !ok {
+		return 0, nil
+	}
+	// Step is the trip trail position, -1 when trips are not collected
 	type state struct {
-		Node  string
-		Stops int
-	}
-	stack := []state{{from, 0}}
-	for len(stack) > 0 {
-		n := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-		if n.Stops > maxStops {
-			continue
-		}
-		if n.Stops >= minStops && n.Node == to {
-			count++
-		}
-		for _, e := range nodes[n.Node] {
-			stack = append(stack, state{e.To, n.Stops + 1})
+		Node  int32
+		Stops int32
+		Step  int32
+	}
+	lo, hi := c.out(src)
+	counts := make([]int, hi-lo)
+	branchStats := opts.branchStats(hi - lo)
+	collectors := make([]*tripCollector, hi-lo)
+	err := forEachBranch(ctx, hi-lo, opts.Workers, func(ctx context.Context, b int) error {
+		cancel := canceller{ctx: ctx}
+		stats := statsFor(branchStats, b)
+		trips := newTripCollector(opts)
+		collectors[b] = trips
+		count := 0
+		stats.relax()
+		stack := []state{{c.targets[lo+b], 1, trips.step(c.targets[lo+b], trips.root(int32(src)))}}
+		stats.push()
+		for len(stack) > 0 {
+			if err := cancel.check(); err != nil {
+				return err
+			}
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			trips.pop(n.Step)
+			if int(n.Stops) > maxStops {
+				stats.prune(PruneMaxStops)
+				continue
+			}
+			stats.expand()
+			if int(n.Stops) >= minStops && int(n.Node) == dst {
+				count++
+				trips.record(n.Step)
+			}
+			lo, hi := c.out(int(n.Node))
+			for i := lo; i < hi; i++ {
+				stats.relax()
+				stack = append(stack, state{c.targets[i], n.Stops + 1, trips.step(c.targets[i], n.Step)})
+				stats.push()
+			}
 		}
+		counts[b] = count
+		return nil
+	})
+	opts.merge(branchStats)
+	if err != nil {
+		return 0, err
 	}
-	return count
+	total := 0
+	var root []Trip
+	// the trip without any stops belongs to no branch
+	if minStops == 0 && src == dst {
+		total++
+		root = []Trip{{Path: []string{from}}}
+	}
+	for _, n := range counts {
+		total += n
+	}
+	opts.mergeTrips(c, root, collectors, total)
+	return total, nil
 }
 
 // CountTripsByDistance counts trips under distance constraint
 func (g *Graph) CountTripsByDistance(from, to string, maxDistance int) int {
+	count, _ := g.CountTripsByDistanceContext(context.Background(), from, to, maxDistance, EnumOptions{})
+	return count
+}
+
+// CountTripsByDistanceContext is CountTripsByDistance split into branches
+// the same way as CountTripsByStopsContext.
+func (g *Graph) CountTripsByDistanceContext(ctx context.Context, from, to string, maxDistance int, opts EnumOptions) (int, error) {
+	defer opts.Stats.timed(time.Now())
 	if maxDistance <= 0 {
-		return 0
+		return 0, nil
 	}
-	nodes := g.snapshotNodes()
-	count := 0
+	c := g.snapshot()
+	src, ok := c.id(from)
+	if !ok {
+		return 0, nil
+	}
+	dst, ok := c.id(to)
+	if !ok {
+		return 0, nil
+	}
+	// Step is the trip trail position, -1 when trips are not collected
 	type state struct {
-		Node     string
+		Node     int32
+		Step     int32
 		Distance int
 	}
-	stack := []state{{from, 0}}
-	for len(stack) > 0 {
-		n := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-		for _, e := range nodes[n.Node] {
-			d := n.Distance + e.Distance
-			if d >= maxDistance {
-				continue
+	lo, hi := c.out(src)
+	counts := make([]int, hi-lo)
+	branchStats := opts.branchStats(hi - lo)
+	collectors := make([]*tripCollector, hi-lo)
+	err := forEachBranch(ctx, hi-lo, opts.Workers, func(ctx context.Context, b int) error {
+		cancel := canceller{ctx: ctx}
+		stats := statsFor(branchStats, b)
+		trips := newTripCollector(opts)
+		collectors[b] = trips
+		count := 0
+		first := lo + b
+		stats.relax()
+		if c.dists[first] >= maxDistance {
+			stats.prune(PruneMaxDistance)
+			return nil
+		}
+		step := trips.step(c.targets[first], trips.root(int32(src)))
+		if int(c.targets[first]) == dst {
+			count++
+			trips.record(step)
+		}
+		stack := []state{{c.targets[first], step, c.dists[first]}}
+		stats.push()
+		for len(stack) > 0 {
+			if err := cancel.check(); err != nil {
+				return err
 			}
-			if e.To == to {
-				count++
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			trips.pop(n.Step)
+			stats.expand()
+			lo, hi := c.out(int(n.Node))
+			for i := lo; i < hi; i++ {
+				stats.relax()
+				d := n.Distance + c.dists[i]
+				if d >= maxDistance {
+					stats.prune(PruneMaxDistance)
+					continue
+				}
+				step := trips.step(c.targets[i], n.Step)
+				if int(c.targets[i]) == dst {
+					count++
+					trips.record(step)
+				}
+				stack = append(stack, state{c.targets[i], step, d})
+				stats.push()
 			}
-			stack = append(stack, state{e.To, d})
 		}
+		counts[b] = count
+		return nil
+	})
+	opts.merge(branchStats)
+	if err != nil {
+		return 0, err
 	}
-	return count
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	opts.mergeTrips(c, nil, collectors, total)
+	return total, nil
 }
 
 // ShortestPath returns shortest distance and path using Dijkstra
 func (g *Graph) ShortestPath(from, to string) (int, []string) {
-	if from == "" || to == "" {
-		return -1, nil
+	dist, path, _ := g.ShortestPathWith(from, to, AlgorithmDijkstra)
+	return dist, path
+}
+
+// shortestPathSearch is the best-first search behind ShortestPath. With a nil
+// heuristic it is Dijkstra, otherwise A*; the heuristic must be consistent.
+// Every town keeps the predecessor it was settled from, and the route always
+// has at least one edge, so from == to yields the shortest cycle.
+func shortestPathSearch(c *csr, from, to int, h func(int) int, stats *SearchStats) (int, []int) {
+	estimate := func(node int) int {
+		if h == nil {
+			return 0
+		}
+		return h(node)
 	}
-	nodes := g.snapshotNodes()
-	visited := make(map[string]int)
+	visited := make([]bool, c.size())
+	parent := make([]int32, c.size())
 	pq := &priorityQueue{}
 	heap.Init(pq)
-	heap.Push(pq, &pqItem{node: from, dist: 0, path: []string{from}})
-
-	shortestDist := -1
-	var shortestPath []string
+	heap.Push(pq, pqItem{node: int32(from), dist: 0, estimate: estimate(from), parent: -1})
+	stats.push()
 
 	for pq.Len() > 0 {
-		curr := heap.Pop(pq).(*pqItem)
-		if val, ok := visited[curr.node]; ok && val <= curr.dist {
+		curr := heap.Pop(pq).(pqItem)
+		node := int(curr.node)
+		if visited[node] {
 			continue
 		}
-		if curr.dist > 0 || curr.node != from {
-			visited[curr.node] = curr.dist
+		if curr.dist > 0 || node != from {
+			visited[node] = true
+			parent[node] = curr.parent
+		}
+		stats.expand()
+
+		if node == to && curr.dist > 0 {
+			return curr.dist, pathTo(parent, from, to)
+		}
+		lo, hi := c.out(node)
+		for i := lo; i < hi; i++ {
+			next := int(c.targets[i])
+			stats.relax()
+			heap.Push(pq, pqItem{node: c.targets[i], dist: curr.dist + c.dists[i], estimate: estimate(next), parent: curr.node})
+			stats.push()
 		}
+	}
+	return -1, nil
+}
 
-		if curr.node == to && curr.dist > 0 {
-			if shortestDist == -1 || curr.dist < shortestDist ||
-				(curr.dist == shortestDist && strings.Join(curr.path, "") < strings.Join(shortestPath, "")) {
-				shortestDist = curr.dist
-				shortestPath = curr.path
+// dijkstraFrom returns the shortest distance (-1 when unreachable) and
+// predecessor of every town from src, with src itself at distance zero.
+func dijkstraFrom(c *csr, src int, stats *SearchStats) ([]int, []int32) {
+	dist := make([]int, c.size())
+	for i := range dist {
+		dist[i] = -1
+	}
+	parent := make([]int32, c.size())
+	pq := &priorityQueue{}
+	heap.Push(pq, pqItem{node: int32(src), parent: -1})
+	stats.push()
+	for pq.Len() > 0 {
+		curr := heap.Pop(pq).(pqItem)
+		if dist[curr.node] >= 0 {
+			continue
+		}
+		dist[curr.node] = curr.dist
+		parent[curr.node] = curr.parent
+		stats.expand()
+		lo, hi := c.out(int(curr.node))
+		for i := lo; i < hi; i++ {
+			stats.relax()
+			if dist[c.targets[i]] < 0 {
+				heap.Push(pq, pqItem{node: c.targets[i], dist: curr.dist + c.dists[i], parent: curr.node})
+				stats.push()
 			}
 		}
-		for _, e := range nodes[curr.node] {
-			heap.Push(pq, &pqItem{node: e.To, dist: curr.dist + e.Distance, path: append(append([]string{}, curr.path...), e.To)})
+	}
+	return dist, parent
+}
+
+// pathTo rebuilds the route from src to dst out of predecessor links,
+// following at least one link so that src == dst yields a cycle.
+func pathTo(parent []int32, src, dst int) []int {
+	path := []int{dst}
+	for cur := dst; ; {
+		cur = int(parent[cur])
+		path = append(path, cur)
+		if cur == src {
+			break
 		}
 	}
-	return shortestDist, shortestPath
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
 }
 
 type pqItem struct {
-	node string
-	dist int
-	path []string
+	node     int32
+	parent   int32
+	dist     int
+	estimate int
 }
-type priorityQueue []*pqItem
+type priorityQueue []pqItem
 
 func (pq priorityQueue) Len() int { return len(pq) }
 func (pq priorityQueue) Less(i, j int) bool {
-	return pq[i].dist < pq[j].dist
+	return pq[i].dist+pq[i].estimate < pq[j].dist+pq[j].estimate
 }
 func (pq priorityQueue) Swap(i, j int)       { pq[i], pq[j] = pq[j], pq[i] }
-func (pq *priorityQueue) Push(x interface{}) { *pq = append(*pq, x.(*pqItem)) }
+func (pq *priorityQueue) Push(x interface{}) { *pq = append(*pq, x.(pqItem)) }
 func (pq *priorityQueue) Pop() interface{} {
 	old := *pq
 	n := len(old)
@@ -250,70 +638,144 @@ func (pq *priorityQueue) Pop() interface{} {
 }
 
 func (g *Graph) SearchRoutes(from, to string, req models.RouteSearchRequest) models.RouteSearchResponse {
-	// DFS to find routes with constraints
+	res, _ := g.SearchRoutesContext(context.Background(), from, to, req, EnumOptions{})
+	return res
+}
+
+// SearchRoutesContext is SearchRoutes with the routes leaving from through
+// each of its edges enumerated as a separate branch, spread across
+// opts.Workers goroutines. Branch results are merged in edge order before
+// sorting, so the response does not depend on the number of workers.
+func (g *Graph) SearchRoutesContext(ctx context.Context, from, to string, req models.RouteSearchRequest, opts EnumOptions) (models.RouteSearchResponse, error) {
+	defer opts.Stats.timed(time.Now())
+	res := models.RouteSearchResponse{}
+	c := g.snapshot()
+	src, ok := c.id(from)
+	if !ok {
+		return res, nil
+	}
+	dst, ok := c.id(to)
+	if !ok {
+		return res, nil
+	}
+
+	// MaxDistance is in distance steps, see DistanceScale, and may fall
+	// between two of them
+	limited, maxDist := req.Constraints.MaxDistance > 0, int(math.Floor(req.Constraints.MaxDistance))
+
+	// DFS to find routes with constraints. Partial routes share their
+	// prefixes through trail, each step pointing at the one before it.
+	type step struct {
+		Node int32
+		Prev int32
+	}
 	type state struct {
-		Path     []string
+		Step     int32
+		Stops    int
 		Distance int
 	}
-	stack := []state{{Path: []string{from}, Distance: 0}}
-	results := []state{}
-	nodes := g.snapshotNodes()
-	for len(stack) > 0 {
-		curr := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-
-		last := curr.Path[len(curr.Path)-1]
-		if last == to && len(curr.Path) > 1 {
-			results = append(results, curr)
+	lo, hi := c.out(src)
+	found := make([][]Route, hi-lo)
+	branchStats := opts.branchStats(hi - lo)
+	err := forEachBranch(ctx, hi-lo, opts.Workers, func(ctx context.Context, b int) error {
+		cancel := canceller{ctx: ctx}
+		stats := statsFor(branchStats, b)
+		trail := []step{{Node: int32(src), Prev: -1}}
+		stack := []state{}
+		results := []state{}
+		visits := func(s int32, node int32) bool {
+			for ; s >= 0; s = trail[s].Prev {
+				if trail[s].Node == node {
+					return true
+				}
+			}
+			return false
 		}
+		push := func(curr state, i int) {
+			newDist := curr.Distance + c.dists[i]
+			stats.relax()
 
-		for _, e := range nodes[last] {
-			newDist := curr.Distance + e.Distance
-			newPath := append(append([]string{}, curr.Path...), e.To)
+			if req.Constraints.MaxStops > 0 && curr.Stops+1 > req.Constraints.MaxStops {
+				stats.prune(PruneMaxStops)
+				return
+			}
+			if limited && newDist > maxDist {
+				stats.prune(PruneMaxDistance)
+				return
+			}
+			// the landmarks may prove the budget cannot get us to the destination
+			if limited && c.alt != nil {
+				if b, ok := c.alt.bound(int(c.targets[i]), dst); !ok || newDist+b > maxDist {
+					stats.prune(PruneLandmarks)
+					return
+				}
+			}
+			if req.Constraints.DistinctNodes && visits(curr.Step, c.targets[i]) {
+				stats.prune(PruneDistinctNodes)
+				return
+			}
+			trail = append(trail, step{Node: c.targets[i], Prev: curr.Step})
+			stack = append(stack, state{Step: int32(len(trail) - 1), Stops: curr.Stops + 1, Distance: newDist})
+			stats.push()
+		}
+		push(state{Step: 0}, lo+b)
+		for len(stack) > 0 {
+			if err := cancel.check(); err != nil {
+				return err
+			}
+			curr := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			stats.expand()
 
-			if req.Constraints.MaxStops > 0 && len(newPath)-1 > req.Constraints.MaxStops {
-				continue
+			last := trail[curr.Step].Node
+			if int(last) == dst {
+				results = append(results, curr)
 			}
-			if req.Constraints.MaxDistance > 0 && newDist > req.Constraints.MaxDistance {
-				continue
+
+			lo, hi := c.out(int(last))
+			for i := lo; i < hi; i++ {
+				push(curr, i)
 			}
-			if req.Constraints.DistinctNodes && containsDuplicate(newPath) {
-				continue
+		}
+
+		routes := make([]Route, len(results))
+		for k, r := range results {
+			path := make([]string, r.Stops+1)
+			for s, i := r.Step, r.Stops; s >= 0; s, i = trail[s].Prev, i-1 {
+				path[i] = c.names[trail[s].Node]
 			}
-			stack = append(stack, state{Path: newPath, Distance: newDist})
+			routes[k] = Route{Path: path, Distance: r.Distance}
 		}
+		found[b] = routes
+		return nil
+	})
+	opts.merge(branchStats)
+	if err != nil {
+		return res, err
+	}
+	var routes []Route
+	for _, r := range found {
+		routes = append(routes, r...)
 	}
 
 	// Sort by distance then lexicographically
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].Distance != results[j].Distance {
-			return results[i].Distance < results[j].Distance
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Distance != routes[j].Distance {
+			return routes[i].Distance < routes[j].Distance
 		}
-		return strings.Join(results[i].Path, "") < strings.Join(results[j].Path, "")
+		return strings.Join(routes[i].Path, "") < strings.Join(routes[j].Path, "")
 	})
 
 	limit := req.Limit
-	if limit <= 0 || limit > len(results) {
-		limit = len(results)
+	if limit <= 0 || limit > len(routes) {
+		limit = len(routes)
 	}
 
-	res := models.RouteSearchResponse{}
-	for _, r := range results[:limit] {
+	for _, r := range routes[:limit] {
 		res.Routes = append(res.Routes, struct {
 			Path     []string `json:"path"`
 			Distance int      `json:"distance"`
 		}{Path: r.Path, Distance: r.Distance})
 	}
-	return res
-}
-
-func containsDuplicate(path []string) bool {
-	set := make(map[string]struct{})
-	for _, n := range path {
-		if _, exists := set[n]; exists {
-			return true
-		}
-		set[n] = struct{}{}
-	}
-	return false
+	return res, nil
 }
@@ -1,9 +1,11 @@
 package graphs
 
 import (
+	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/aashi1008/hamburg-rails/internal/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -149,3 +151,77 @@ func FuzzCountTripsByStops_MinGreaterThanMax(f *testing.F) {
 		}
 	})
 }
+
+// denseGraph connects every pair of the 26 single-letter towns with
+// pseudo-random distances.
+func denseGraph(b testing.TB) *Graph {
+	var edges []string
+	seed := 1
+	for from := 'A'; from <= 'Z'; from++ {
+		for to := 'A'; to <= 'Z'; to++ {
+			if from == to {
+				continue
+			}
+			seed = (seed*1103515245 + 12345) % 2147483648
+			if seed%3 == 0 {
+				edges = append(edges, string(from)+string(to)+strconv.Itoa(1+seed%40))
+			}
+		}
+	}
+	g := NewGraph()
+	if err := g.LoadEdges(edges); err != nil {
+		b.Fatal(err)
+	}
+	return g
+}
+
+// The benchmarks below were added with the CSR storage. Before it, on the
+// map of edge slices, -benchtime 2000x gave:
+//
+//	Distance               401 B/op     2 allocs/op
+//	ShortestPath         50092 B/op   543 allocs/op
+//	CountTripsByStops     4948 B/op    10 allocs/op
+//	CountTripsByDistance  3156 B/op     9 allocs/op
+//	SearchRoutes       2828876 B/op 15265 allocs/op
+
+func BenchmarkDistance(b *testing.B) {
+	g := seedGraph()
+	path := []string{"A", "E", "B", "C", "D"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = g.Distance(path)
+	}
+}
+
+func BenchmarkShortestPath(b *testing.B) {
+	g := denseGraph(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = g.ShortestPath("A", "Z")
+	}
+}
+
+func BenchmarkCountTripsByStops(b *testing.B) {
+	g := denseGraph(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = g.CountTripsByStops("A", "Z", 1, 4)
+	}
+}
+
+func BenchmarkCountTripsByDistance(b *testing.B) {
+	g := denseGraph(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = g.CountTripsByDistance("A", "Z", 40)
+	}
+}
+
+func BenchmarkSearchRoutes(b *testing.B) {
+	g := denseGraph(b)
+	req := models.RouteSearchRequest{Constraints: models.RouteSearchConstraints{MaxDistance: 35}, Limit: 10}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = g.SearchRoutes("A", "Z", req)
+	}
+}
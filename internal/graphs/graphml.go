@@ -0,0 +1,112 @@
+package graphs
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type graphmlDoc struct {
+	XMLName xml.Name       `xml:"graphml"`
+	XMLNS   string         `xml:"xmlns,attr,omitempty"`
+	Keys    []graphmlKey   `xml:"key"`
+	Graphs  []graphmlGraph `xml:"graph"`
+}
+
+type graphmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr,omitempty"`
+}
+
+type graphmlGraph struct {
+	ID          string        `xml:"id,attr,omitempty"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed string        `xml:"directed,attr,omitempty"`
+	Data     []graphmlData `xml:"data"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// importGraphML reads the nodes and edges of every graph in a GraphML
+// document. Towns are node ids, or the node's name or label attribute when
+// it has one. Edge distances come from a distance or weight attribute and
+// capacities from a capacity attribute. Undirected edges are loaded in both
+// directions.
+func importGraphML(r io.Reader) ([]EdgeSpec, error) {
+	var doc graphmlDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid graphml: %v", err)
+	}
+	// attribute name by key id
+	attrs := make(map[string]string, len(doc.Keys))
+	for _, k := range doc.Keys {
+		attrs[k.ID] = strings.ToLower(k.Name)
+	}
+	var specs []EdgeSpec
+	for _, gr := range doc.Graphs {
+		names := make(map[string]string, len(gr.Nodes))
+		for _, n := range gr.Nodes {
+			names[n.ID] = n.ID
+			for _, d := range n.Data {
+				if a := attrs[d.Key]; a == "name" || a == "label" {
+					names[n.ID] = strings.TrimSpace(d.Value)
+				}
+			}
+		}
+		town := func(id string) string {
+			if name, ok := names[id]; ok {
+				return name
+			}
+			return id
+		}
+		for i, e := range gr.Edges {
+			spec := EdgeSpec{From: town(e.Source), To: town(e.Target)}
+			found := false
+			for _, d := range e.Data {
+				value := strings.TrimSpace(d.Value)
+				var err error
+				switch attrs[d.Key] {
+				case "distance", "weight":
+					found = true
+					spec.Distance, spec.Scale, err = parseDistance(value)
+				case "capacity":
+					spec.Capacity, err = strconv.Atoi(value)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("graphml edge %d (%s->%s): invalid %s %q", i, e.Source, e.Target, attrs[d.Key], value)
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("graphml edge %d (%s->%s): no distance", i, e.Source, e.Target)
+			}
+			specs = append(specs, spec)
+			directed := gr.EdgeDefault != "undirected"
+			if e.Directed != "" {
+				directed = e.Directed == "true"
+			}
+			if !directed {
+				specs = append(specs, EdgeSpec{From: spec.To, To: spec.From, Distance: spec.Distance, Scale: spec.Scale, Capacity: spec.Capacity})
+			}
+		}
+	}
+	return specs, nil
+}
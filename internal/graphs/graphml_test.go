@@ -0,0 +1,42 @@
+package graphs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportGraphML(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="d0" for="edge" attr.name="distance" attr.type="int"/>
+  <key id="d1" for="edge" attr.name="capacity" attr.type="int"/>
+  <key id="d2" for="node" attr.name="name" attr.type="string"/>
+  <graph id="G" edgedefault="directed">
+    <node id="n0"><data key="d2">Hamburg</data></node>
+    <node id="n1"><data key="d2">Bremen</data></node>
+    <node id="C"/>
+    <edge source="n0" target="n1"><data key="d0">120</data><data key="d1">4</data></edge>
+    <edge source="n1" target="C" directed="false"><data key="d0">7</data></edge>
+  </graph>
+</graphml>`
+	g := NewGraph()
+	assert.NoError(t, g.Import(strings.NewReader(doc), FormatGraphML))
+	assert.Equal(t, map[string][]Edge{
+		"HAMBURG": {{To: "BREMEN", Distance: 120, Capacity: 4}},
+		"BREMEN":  {{To: "C", Distance: 7}},
+		"C":       {{To: "BREMEN", Distance: 7}},
+	}, g.Nodes())
+}
+
+func TestImportGraphMLErrors(t *testing.T) {
+	g := NewGraph()
+	assert.Error(t, g.Import(strings.NewReader("<graphml><graph>"), FormatGraphML))
+
+	noDistance := `<graphml><graph edgedefault="directed"><edge source="A" target="B"/></graph></graphml>`
+	assert.EqualError(t, g.Import(strings.NewReader(noDistance), FormatGraphML), "graphml edge 0 (A->B): no distance")
+
+	badWeight := `<graphml><key id="w" for="edge" attr.name="weight"/><graph><edge source="A" target="B"><data key="w">1.5e3</data></edge></graph></graphml>`
+	assert.EqualError(t, g.Import(strings.NewReader(badWeight), FormatGraphML), `graphml edge 0 (A->B): invalid weight "1.5e3"`)
+}
@@ -0,0 +1,308 @@
+package graphs
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// gtfsImporter builds the station graph of the rail routes in a GTFS static
+// feed. Platforms are merged into their parent station, and every pair of
+// consecutive stations on a trip becomes an edge weighted with the shortest
+// scheduled travel time between them, in seconds.
+type gtfsImporter struct{}
+
+func (gtfsImporter) Import(r io.Reader) ([]EdgeSpec, error) {
+	res, err := gtfsImporter{}.ImportResult(r, ImportOptions{})
+	return res.Edges, err
+}
+
+func (gtfsImporter) ImportResult(r io.Reader, _ ImportOptions) (ImportResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("invalid gtfs zip: %v", err)
+	}
+	feed := &gtfsFeed{files: map[string]*zip.File{}}
+	for _, f := range archive.File {
+		// feeds are sometimes zipped with an enclosing directory
+		name := f.Name[strings.LastIndex(f.Name, "/")+1:]
+		feed.files[name] = f
+	}
+	specs, err := feed.build()
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return ImportResult{Edges: specs, Metadata: Metadata{Units: "s"}, Warnings: feed.warnings()}, nil
+}
+
+type gtfsFeed struct {
+	files map[string]*zip.File
+	importWarnings
+}
+
+// each calls fn with every record of a feed file as a map from column name
+// to value, along with its line number.
+func (f *gtfsFeed) each(name string, fn func(line int, row map[string]string) error) error {
+	file, ok := f.files[name]
+	if !ok {
+		return fmt.Errorf("gtfs feed has no %s", name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	defer rc.Close()
+	cr := csv.NewReader(rc)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("%s: missing header: %v", name, err)
+	}
+	columns := make([]string, len(header))
+	for i, h := range header {
+		columns[i] = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+	}
+	row := make(map[string]string, len(columns))
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) && perr.Err == csv.ErrFieldCount {
+				f.warn(name, line, "wrong number of fields")
+				continue
+			}
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		for i, c := range columns {
+			row[c] = ""
+			if i < len(record) {
+				row[c] = strings.TrimSpace(record[i])
+			}
+		}
+		if err := fn(line, row); err != nil {
+			return err
+		}
+	}
+}
+
+// gtfsRailRoute reports whether a route_type is a train: 2 in the basic
+// types, 100-199 in the extended ones.
+func gtfsRailRoute(routeType string) bool {
+	t, err := strconv.Atoi(routeType)
+	return err == nil && (t == 2 || t >= 100 && t < 200)
+}
+
+// gtfsSeconds parses an H:MM:SS time, which may be past 24:00:00.
+func gtfsSeconds(s string) (int, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	total := 0
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || i > 0 && (len(p) != 2 || n > 59) {
+			return 0, false
+		}
+		total = total*60 + n
+	}
+	return total, true
+}
+
+type gtfsStopTime struct {
+	seq       int
+	station   string
+	arrival   int
+	departure int
+	line      int
+}
+
+func (f *gtfsFeed) build() ([]EdgeSpec, error) {
+	rail := map[string]bool{}
+	err := f.each("routes.txt", func(line int, row map[string]string) error {
+		if gtfsRailRoute(row["route_type"]) {
+			rail[row["route_id"]] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rail) == 0 {
+		return nil, fmt.Errorf("gtfs feed has no rail routes")
+	}
+
+	trips := map[string]bool{}
+	err = f.each("trips.txt", func(line int, row map[string]string) error {
+		if rail[row["route_id"]] {
+			trips[row["trip_id"]] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// station of every stop, and name of every station
+	station := map[string]string{}
+	names := map[string]string{}
+	err = f.each("stops.txt", func(line int, row map[string]string) error {
+		id := row["stop_id"]
+		if parent := row["parent_station"]; parent != "" {
+			station[id] = parent
+		} else {
+			station[id] = id
+			names[id] = row["stop_name"]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byTrip := map[string][]gtfsStopTime{}
+	err = f.each("stop_times.txt", func(line int, row map[string]string) error {
+		trip := row["trip_id"]
+		if !trips[trip] {
+			return nil
+		}
+		st, ok := station[row["stop_id"]]
+		if !ok {
+			f.warn("stop_times.txt", line, "unknown stop_id %q", row["stop_id"])
+			return nil
+		}
+		seq, err := strconv.Atoi(row["stop_sequence"])
+		if err != nil {
+			f.warn("stop_times.txt", line, "invalid stop_sequence %q", row["stop_sequence"])
+			return nil
+		}
+		t := gtfsStopTime{seq: seq, station: st, arrival: -1, departure: -1, line: line}
+		for _, field := range []struct {
+			column string
+			value  *int
+		}{{"arrival_time", &t.arrival}, {"departure_time", &t.departure}} {
+			raw := row[field.column]
+			if raw == "" {
+				continue
+			}
+			secs, ok := gtfsSeconds(raw)
+			if !ok {
+				f.warn("stop_times.txt", line, "invalid %s %q", field.column, raw)
+				return nil
+			}
+			*field.value = secs
+		}
+		byTrip[trip] = append(byTrip[trip], t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// shortest travel time in seconds between consecutive stations
+	fastest := map[[2]string]int{}
+	tripIDs := make([]string, 0, len(byTrip))
+	for id := range byTrip {
+		tripIDs = append(tripIDs, id)
+	}
+	sort.Strings(tripIDs)
+	for _, id := range tripIDs {
+		times := byTrip[id]
+		sort.Slice(times, func(i, j int) bool { return times[i].seq < times[j].seq })
+		for k := 1; k < len(times); k++ {
+			from, to := times[k-1], times[k]
+			if from.station == to.station {
+				continue
+			}
+			leave, arrive := from.departure, to.arrival
+			if leave < 0 {
+				leave = from.arrival
+			}
+			if arrive < 0 {
+				arrive = to.departure
+			}
+			if leave < 0 || arrive < 0 {
+				f.warn("stop_times.txt", to.line, "trip %q has no time between %s and %s", id, from.station, to.station)
+				continue
+			}
+			if arrive < leave {
+				f.warn("stop_times.txt", to.line, "trip %q arrives at %s before leaving %s", id, to.station, from.station)
+				continue
+			}
+			key := [2]string{from.station, to.station}
+			if d, ok := fastest[key]; !ok || arrive-leave < d {
+				fastest[key] = arrive - leave
+			}
+		}
+	}
+
+	towns := f.townNames(fastest, names)
+	specs := make([]EdgeSpec, 0, len(fastest))
+	for key, secs := range fastest {
+		from, okFrom := towns[key[0]]
+		to, okTo := towns[key[1]]
+		if !okFrom || !okTo {
+			continue
+		}
+		// whole seconds, so sums of legs stay exact; at least one so every
+		// edge has a positive weight
+		specs = append(specs, EdgeSpec{From: from, To: to, Distance: max(1, secs)})
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		if specs[i].From != specs[j].From {
+			return specs[i].From < specs[j].From
+		}
+		return specs[i].To < specs[j].To
+	})
+	return specs, nil
+}
+
+// townNames turns the names of the stations used by edges into town names:
+// upper-cased letters only, German umlauts spelled out, at most 16 long.
+// Stations whose names collide get a letter suffix in stop_id order.
+func (f *gtfsFeed) townNames(edges map[[2]string]int, names map[string]string) map[string]string {
+	var stations []string
+	seen := map[string]bool{}
+	for key := range edges {
+		for _, s := range key {
+			if !seen[s] {
+				seen[s] = true
+				stations = append(stations, s)
+			}
+		}
+	}
+	sort.Strings(stations)
+
+	towns := make(map[string]string, len(stations))
+	namer := newTownNamer()
+	for _, s := range stations {
+		town, base, ok := namer.name(names[s])
+		switch {
+		case base == "":
+			f.warn("stops.txt", 0, "station %q has no usable name", s)
+		case !ok:
+			f.warn("stops.txt", 0, "station %q has the same name as too many others", s)
+		case town != base:
+			f.warn("stops.txt", 0, "station %q renamed %s, %s is taken", s, town, base)
+		}
+		if ok {
+			towns[s] = town
+		}
+	}
+	return towns
+}
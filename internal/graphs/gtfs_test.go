@@ -0,0 +1,104 @@
+package graphs
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gtfsZip zips the given feed files.
+func gtfsZip(t *testing.T, files map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(body))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return &buf
+}
+
+func testFeed() map[string]string {
+	return map[string]string{
+		"routes.txt": "route_id,route_short_name,route_type\nRE1,RE1,2\nICE,ICE,101\nBUS,5,3\n",
+		"trips.txt":  "route_id,service_id,trip_id\nRE1,wk,t1\nICE,wk,t2\nBUS,wk,t3\n",
+		"stops.txt": "\ufeffstop_id,stop_name,location_type,parent_station\n" +
+			"hh,Hamburg Hbf,1,\nhh1,Hamburg Hbf Gleis 1,0,hh\nhb,Bremen Hbf,1,\nos,Osnabrück Hbf,1,\n" +
+			"os2,Osnabrück Hbf Gleis 2,0,os\nx,Bushof,0,\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			// the regional train takes 70 minutes to Bremen
+			"t1,08:00:00,08:00:00,hh1,1\nt1,09:10:00,09:12:00,hb,2\nt1,10:05:30,10:06:00,os2,3\n" +
+			// the ICE takes 55 minutes, listed out of order and past midnight
+			"t2,24:50:00,24:50:00,hb,2\nt2,23:55:00,23:55:00,hh,1\nt2,25:40:00,,os,3\n" +
+			"t3,08:00:00,08:00:00,x,1\nt3,08:10:00,08:10:00,hh,2\n" +
+			"t1,bad,10:00:00,hb,4\nt2,26:00:00,26:00:00,nowhere,4\n",
+	}
+}
+
+func TestImportGTFS(t *testing.T) {
+	g := NewGraph()
+	warnings, err := g.ImportWithWarnings(gtfsZip(t, testFeed()), FormatGTFS)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]Edge{
+		"HAMBURGHBF": {{To: "BREMENHBF", Distance: 3300}},
+		"BREMENHBF":  {{To: "OSNABRUECKHBF", Distance: 3000}},
+	}, g.Nodes())
+	assert.Equal(t, Metadata{Units: "s"}, g.Metadata())
+	assert.Equal(t, []ImportWarning{
+		{File: "stop_times.txt", Line: 10, Message: `invalid arrival_time "bad"`},
+		{File: "stop_times.txt", Line: 11, Message: `unknown stop_id "nowhere"`},
+	}, warnings)
+}
+
+func TestImportGTFSNames(t *testing.T) {
+	feed := testFeed()
+	// a second station also called Hamburg Hbf
+	feed["stops.txt"] = "stop_id,stop_name,parent_station\nhh,Hamburg Hbf,\nhh1,Hamburg Hbf,\nhb,Bremen Hbf,\nos,Osnabrück Hbf,\nos2,X,os\nx,Bushof,\n"
+	feed["stop_times.txt"] = "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+		"t1,08:00:00,08:00:00,hh1,1\nt1,08:30:00,08:30:00,hh,2\nt1,09:00:01,,hb,3\n"
+	g := NewGraph()
+	warnings, err := g.ImportWithWarnings(gtfsZip(t, feed), FormatGTFS)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]Edge{
+		"HAMBURGHBFA": {{To: "HAMBURGHBF", Distance: 1800}},
+		"HAMBURGHBF":  {{To: "BREMENHBF", Distance: 1801}},
+	}, g.Nodes())
+	// times are kept to the second, so the legs add up exactly
+	dist, err := g.Distance([]string{"HAMBURGHBFA", "HAMBURGHBF", "BREMENHBF"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3601, dist)
+	conv, err := g.Converter("minutes")
+	assert.NoError(t, err)
+	assert.Equal(t, 60.017, conv.Value(dist))
+	assert.Equal(t, []ImportWarning{{File: "stops.txt", Message: "station \"hh1\" renamed HAMBURGHBFA, HAMBURGHBF is taken"}}, warnings)
+}
+
+func TestImportGTFSErrors(t *testing.T) {
+	g := NewGraph()
+	feed := testFeed()
+	delete(feed, "stop_times.txt")
+	assert.EqualError(t, g.Import(gtfsZip(t, feed), FormatGTFS), "gtfs feed has no stop_times.txt")
+
+	feed = testFeed()
+	feed["routes.txt"] = "route_id,route_type\nBUS,3\n"
+	assert.EqualError(t, g.Import(gtfsZip(t, feed), FormatGTFS), "gtfs feed has no rail routes")
+
+	assert.Error(t, g.Import(bytes.NewReader([]byte("AB5")), FormatGTFS))
+	assert.Equal(t, FormatGTFS, ImportFormat("feed.zip", ""))
+}
+
+func TestGTFSSeconds(t *testing.T) {
+	for s, want := range map[string]int{"8:00:00": 28800, "25:01:02": 90062} {
+		got, ok := gtfsSeconds(s)
+		assert.True(t, ok)
+		assert.Equal(t, want, got)
+	}
+	for _, s := range []string{"", "8:00", "08:60:00", "08:0:00", "-1:00:00"} {
+		_, ok := gtfsSeconds(s)
+		assert.False(t, ok, s)
+	}
+}
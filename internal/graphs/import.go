@@ -0,0 +1,474 @@
+package graphs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Import formats registered by this package.
+const (
+	FormatText    = "text"
+	FormatCSV     = "csv"
+	FormatJSON    = "json"
+	FormatGraphML = "graphml"
+	FormatDOT     = "dot"
+	FormatGTFS    = "gtfs"
+	FormatOSM     = "osm"
+	FormatOSMPBF  = "osm-pbf"
+)
+
+// Importer reads the edges of a graph in one file format. The edges are
+// validated by LoadEdgeSpecs, so importers only report syntax errors. An
+// importer may return the edges it could read along with ParseErrors for
+// the rest; those edges are still validated so that every problem with the
+// input is reported at once.
+type Importer interface {
+	Import(r io.Reader) ([]EdgeSpec, error)
+}
+
+// ImporterFunc adapts a function to the Importer interface.
+type ImporterFunc func(r io.Reader) ([]EdgeSpec, error)
+
+func (f ImporterFunc) Import(r io.Reader) ([]EdgeSpec, error) { return f(r) }
+
+type importerEntry struct {
+	importer   Importer
+	extensions []string
+	mediaTypes []string
+}
+
+var (
+	importersMu sync.RWMutex
+	importers   = map[string]importerEntry{}
+)
+
+func init() {
+	RegisterImporter(FormatText, textImporter{}, []string{".txt"}, []string{"text/plain"})
+	RegisterImporter(FormatCSV, ImporterFunc(importCSV), []string{".csv"}, []string{"text/csv"})
+	RegisterImporter(FormatJSON, ImporterFunc(importJSON), []string{".json"}, []string{"application/json"})
+	RegisterImporter(FormatGraphML, ImporterFunc(importGraphML), []string{".graphml"},
+		[]string{"application/graphml+xml", "application/xml", "text/xml"})
+	RegisterImporter(FormatDOT, ImporterFunc(importDOT), []string{".dot", ".gv"}, []string{"text/vnd.graphviz"})
+	RegisterImporter(FormatGTFS, gtfsImporter{}, []string{".zip"}, []string{"application/zip", "application/x-zip-compressed"})
+	RegisterImporter(FormatOSM, osmImporter{}, []string{".osm"}, []string{"application/vnd.openstreetmap.data+xml"})
+	RegisterImporter(FormatOSMPBF, osmImporter{pbf: true}, []string{".pbf"}, []string{"application/vnd.openstreetmap.data+pbf"})
+}
+
+// RegisterImporter makes imp available as format, picked by ImportFormat
+// for files with one of the given extensions (with the leading dot) and
+// uploads with one of the given media types. Registering a format again
+// replaces it.
+func RegisterImporter(format string, imp Importer, extensions, mediaTypes []string) {
+	importersMu.Lock()
+	defer importersMu.Unlock()
+	importers[format] = importerEntry{importer: imp, extensions: extensions, mediaTypes: mediaTypes}
+}
+
+// ImportFormat returns the registered format for a file name, going by its
+// extension, or failing that for a Content-Type header. It returns "" when
+// neither is recognised.
+func ImportFormat(filename, contentType string) string {
+	importersMu.RLock()
+	defer importersMu.RUnlock()
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != "" {
+		for format, e := range importers {
+			for _, x := range e.extensions {
+				if x == ext {
+					return format
+				}
+			}
+		}
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		for format, e := range importers {
+			for _, m := range e.mediaTypes {
+				if m == mediaType {
+					return format
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// ImportFormats returns the names of the registered formats.
+func ImportFormats() []string {
+	importersMu.RLock()
+	defer importersMu.RUnlock()
+	formats := make([]string, 0, len(importers))
+	for f := range importers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// ImportWarning describes input an importer skipped without failing.
+type ImportWarning struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// maxImportWarnings bounds the warnings kept for one import; the rest are
+// summarised in a final warning.
+const maxImportWarnings = 100
+
+// importWarnings collects the warnings of one import, keeping the first
+// maxImportWarnings.
+type importWarnings struct {
+	skipped []ImportWarning
+	dropped int
+}
+
+func (iw *importWarnings) warn(file string, line int, format string, args ...interface{}) {
+	if len(iw.skipped) == maxImportWarnings {
+		iw.dropped++
+		return
+	}
+	iw.skipped = append(iw.skipped, ImportWarning{File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (iw *importWarnings) warnings() []ImportWarning {
+	if iw.dropped > 0 {
+		return append(iw.skipped, ImportWarning{Message: fmt.Sprintf("%d more rows skipped", iw.dropped)})
+	}
+	return iw.skipped
+}
+
+// townNamer turns the free-form station names of real-world feeds into
+// unique town names.
+type townNamer struct {
+	taken map[string]bool
+}
+
+func newTownNamer() *townNamer {
+	return &townNamer{taken: map[string]bool{}}
+}
+
+var umlauts = strings.NewReplacer("Ä", "AE", "Ö", "OE", "Ü", "UE", "ẞ", "SS", "ß", "SS")
+
+// name returns the town for a station name: its letters upper-cased, German
+// umlauts spelled out, cut to 16. base is that name, and town differs from
+// it by a letter suffix when an earlier station took it. ok is false when
+// base is empty or every suffix is taken.
+func (n *townNamer) name(station string) (town, base string, ok bool) {
+	var b strings.Builder
+	for _, r := range umlauts.Replace(strings.ToUpper(station)) {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteRune(r)
+		}
+	}
+	base = b.String()
+	if base == "" {
+		return "", "", false
+	}
+	if len(base) > 16 {
+		base = base[:16]
+	}
+	town = base
+	for k := 0; n.taken[town] && k < 26; k++ {
+		town = base[:min(len(base), 15)] + string(rune('A'+k))
+	}
+	if n.taken[town] {
+		return "", base, false
+	}
+	n.taken[town] = true
+	return town, base, true
+}
+
+// Metadata describes a loaded graph, as declared in the header of a text
+// graph file.
+type Metadata struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	Units   string `json:"units,omitempty"`
+}
+
+// ImportOptions is what an import may need beyond its input.
+type ImportOptions struct {
+	// Includes holds the files the input may include, with paths relative
+	// to the input. Includes are refused when it is nil.
+	Includes fs.FS
+	// Name is the path of the input in Includes, if it is there, so that
+	// it cannot include itself.
+	Name string
+	// Mirror adds the reverse of every edge whose reverse is not listed,
+	// with the same distance and capacity.
+	Mirror bool
+	// DryRun checks the input without loading it. The report then also
+	// lists likely mistakes, and is filled in from the valid edges even
+	// when the input has errors.
+	DryRun bool
+}
+
+// ImportResult is everything an importer read from its input.
+type ImportResult struct {
+	Edges    []EdgeSpec
+	Metadata Metadata
+	// Warnings lists the input the importer skipped.
+	Warnings []ImportWarning
+}
+
+// ResultImporter is an Importer that can also report the input it skipped
+// and the metadata it read, and resolve includes.
+type ResultImporter interface {
+	Importer
+	ImportResult(r io.Reader, opts ImportOptions) (ImportResult, error)
+}
+
+// LoadReport describes a successful load beyond the edges loaded.
+type LoadReport struct {
+	// Warnings lists the input the importer skipped.
+	Warnings []ImportWarning `json:"warnings,omitempty"`
+	// Asymmetric lists the first maxImportWarnings of the AsymmetricCount
+	// pairs of towns not joined the same way in both directions.
+	Asymmetric      []AsymmetricPair `json:"asymmetric,omitempty"`
+	AsymmetricCount int              `json:"asymmetricCount,omitempty"`
+	// Lint lists the first maxImportWarnings of the LintCount likely
+	// mistakes found by a dry run.
+	Lint      []LintWarning `json:"lint,omitempty"`
+	LintCount int           `json:"lintCount,omitempty"`
+}
+
+// Import reads a graph in the given format and replaces the graph data with
+// it, applying the same validation as LoadEdges.
+func (g *Graph) Import(r io.Reader, format string) error {
+	_, err := g.ImportWithWarnings(r, format)
+	return err
+}
+
+// ImportWithWarnings is Import also returning the input the importer
+// skipped, for importers that report it.
+func (g *Graph) ImportWithWarnings(r io.Reader, format string) ([]ImportWarning, error) {
+	report, err := g.ImportWithOptions(r, format, ImportOptions{})
+	return report.Warnings, err
+}
+
+// ImportWithOptions is Import with options, also returning the report of
+// the load.
+func (g *Graph) ImportWithOptions(r io.Reader, format string, opts ImportOptions) (LoadReport, error) {
+	importersMu.RLock()
+	e, ok := importers[format]
+	importersMu.RUnlock()
+	if !ok {
+		return LoadReport{}, fmt.Errorf("unsupported graph format: %q", format)
+	}
+	var res ImportResult
+	var err error
+	if ri, ok := e.importer.(ResultImporter); ok {
+		res, err = ri.ImportResult(r, opts)
+	} else {
+		res.Edges, err = e.importer.Import(r)
+	}
+	var errs ParseErrors
+	if err != nil && !errors.As(err, &errs) {
+		return LoadReport{}, err
+	}
+	return g.loadSpecs(res, opts, errs)
+}
+
+// importCSV reads `from,to,distance[,capacity]` records. A first record
+// starting with the column name "from" is a header, which may list the
+// columns in any order.
+func importCSV(r io.Reader) ([]EdgeSpec, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+	columns := map[string]int{"from": 0, "to": 1, "distance": 2, "capacity": 3}
+	var specs []EdgeSpec
+	var errs ParseErrors
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return specs, errs.err()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "from") {
+			columns = map[string]int{}
+			for i, name := range record {
+				columns[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			for _, name := range []string{"from", "to", "distance"} {
+				if _, ok := columns[name]; !ok {
+					return nil, fmt.Errorf("csv header has no %q column", name)
+				}
+			}
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		src := Source{Line: line, Column: 1, Text: strings.Join(record, ",")}
+		field := func(name string) (string, Source) {
+			if i, ok := columns[name]; ok && i < len(record) {
+				line, column := cr.FieldPos(i)
+				return strings.TrimSpace(record[i]), Source{Line: line, Column: column, Text: record[i]}
+			}
+			return "", src
+		}
+		from, _ := field("from")
+		to, _ := field("to")
+		spec := EdgeSpec{From: from, To: to, Source: src}
+		distance, at := field("distance")
+		if spec.Distance, spec.Scale, err = parseDistance(distance); err != nil {
+			errs.add(at, err)
+			continue
+		}
+		if c, at := field("capacity"); c != "" {
+			if spec.Capacity, err = strconv.Atoi(c); err != nil {
+				errs.add(at, ErrInvalidCapacity)
+				continue
+			}
+		}
+		specs = append(specs, spec)
+	}
+}
+
+// importJSON reads an array of edges, either objects with from, to,
+// distance and optional capacity or `AB5` token strings, or an object
+// holding such an array under "edges".
+func importJSON(r io.Reader) ([]EdgeSpec, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	items, offsets, err := jsonEdgeItems(body)
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]EdgeSpec, 0, len(items))
+	var errs ParseErrors
+	for i, item := range items {
+		src := jsonSource(body, offsets[i])
+		src.Text = string(item)
+		var token string
+		if json.Unmarshal(item, &token) == nil {
+			src.Text = token
+			parsed, perrs := parseTokens([]Source{src})
+			specs = append(specs, parsed...)
+			errs = append(errs, perrs...)
+			continue
+		}
+		var edge struct {
+			EdgeSpec
+			// read as text so that decimals keep their exact value
+			Distance json.Number `json:"distance"`
+		}
+		if err := json.Unmarshal(item, &edge); err != nil {
+			errs.add(src, fmt.Errorf("%w: %v", ErrInvalidToken, err))
+			continue
+		}
+		spec := edge.EdgeSpec
+		spec.Source = src
+		var err error
+		if spec.Distance, spec.Scale, err = parseDistance(edge.Distance.String()); err != nil {
+			errs.add(src, err)
+			continue
+		}
+		specs = append(specs, spec)
+	}
+	return specs, errs.err()
+}
+
+// jsonEdgeItems returns the items of the top-level array of body, or of
+// the array under its "edges" key, with the offset at which each starts.
+func jsonEdgeItems(body []byte) ([]json.RawMessage, []int, error) {
+	shape := fmt.Errorf("json graph must be an array of edges or an object with an edges array")
+	dec := json.NewDecoder(bytes.NewReader(body))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid json: %v", err)
+	}
+	object := tok == json.Delim('{')
+	if object {
+		for {
+			if !dec.More() {
+				return nil, nil, shape
+			}
+			key, err := dec.Token()
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid json: %v", err)
+			}
+			if key == "edges" {
+				if tok, err = dec.Token(); err != nil {
+					return nil, nil, fmt.Errorf("invalid json: %v", err)
+				}
+				break
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, nil, fmt.Errorf("invalid json: %v", err)
+			}
+		}
+	}
+	if tok != json.Delim('[') {
+		return nil, nil, shape
+	}
+	var items []json.RawMessage
+	var offsets []int
+	for dec.More() {
+		// the offset is that of the end of the previous item
+		start := int(dec.InputOffset())
+		for start < len(body) && strings.ContainsRune(" \t\r\n,", rune(body[start])) {
+			start++
+		}
+		var item json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			return nil, nil, fmt.Errorf("invalid json: %v", err)
+		}
+		items = append(items, item)
+		offsets = append(offsets, start)
+	}
+	if err := jsonEnd(dec, object); err != nil {
+		return nil, nil, err
+	}
+	return items, offsets, nil
+}
+
+// jsonEnd reads the rest of a JSON graph after its last edge: the closing
+// bracket, the other keys of the object the array was in, and nothing more.
+// A truncated document must not load as the edges read so far.
+func jsonEnd(dec *json.Decoder, object bool) error {
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("invalid json: %v", err)
+	}
+	for object && dec.More() {
+		var skip json.RawMessage
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("invalid json: %v", err)
+		}
+		if err := dec.Decode(&skip); err != nil {
+			return fmt.Errorf("invalid json: %v", err)
+		}
+	}
+	if object {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('}') {
+			return fmt.Errorf("invalid json: unexpected end of object")
+		}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid json: unexpected data after the graph")
+	}
+	return nil
+}
+
+// jsonSource returns the line and column of a byte offset in body.
+func jsonSource(body []byte, offset int) Source {
+	line := 1 + bytes.Count(body[:offset], []byte("\n"))
+	lineStart := bytes.LastIndexByte(body[:offset], '\n') + 1
+	return Source{Line: line, Column: 1 + utf8.RuneCount(body[lineStart:offset])}
+}
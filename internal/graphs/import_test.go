@@ -0,0 +1,109 @@
+package graphs
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportFormat(t *testing.T) {
+	assert.Equal(t, FormatCSV, ImportFormat("network.CSV", ""))
+	assert.Equal(t, FormatDOT, ImportFormat("network.gv", "application/json"))
+	assert.Equal(t, FormatJSON, ImportFormat("", "application/json; charset=utf-8"))
+	assert.Equal(t, FormatGraphML, ImportFormat("upload", "application/graphml+xml"))
+	assert.Equal(t, FormatText, ImportFormat("graph.txt", ""))
+	assert.Equal(t, "", ImportFormat("graph", "application/x-www-form-urlencoded"))
+	assert.Contains(t, ImportFormats(), FormatDOT)
+}
+
+func TestImportText(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.Import(strings.NewReader("AB5, BC4\nCD8/3\n\n"), FormatText))
+	assert.Equal(t, map[string][]Edge{
+		"A": {{To: "B", Distance: 5}},
+		"B": {{To: "C", Distance: 4}},
+		"C": {{To: "D", Distance: 8, Capacity: 3}},
+	}, g.Nodes())
+}
+
+func TestImportCSV(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.Import(strings.NewReader("a,b,5\n# comment\nb, c, 4, 2\n"), FormatCSV))
+	assert.Equal(t, map[string][]Edge{
+		"A": {{To: "B", Distance: 5}},
+		"B": {{To: "C", Distance: 4, Capacity: 2}},
+	}, g.Nodes())
+
+	// a header may reorder the columns
+	assert.NoError(t, g.Import(strings.NewReader("from,distance,to\nhamburg,290,berlin\n"), FormatCSV))
+	assert.Equal(t, map[string][]Edge{"HAMBURG": {{To: "BERLIN", Distance: 290}}}, g.Nodes())
+
+	err := g.Import(strings.NewReader("A,B,5\nB,C,x\n"), FormatCSV)
+	assert.EqualError(t, err, `line 2, column 5: "x": invalid distance`)
+	err = g.Import(strings.NewReader("from,to,weight\nA,B,5\n"), FormatCSV)
+	assert.Error(t, err)
+}
+
+func TestImportJSON(t *testing.T) {
+	g := NewGraph()
+	body := `[{"from":"A","to":"B","distance":5,"capacity":2},"BC4"]`
+	assert.NoError(t, g.Import(strings.NewReader(body), FormatJSON))
+	assert.Equal(t, map[string][]Edge{
+		"A": {{To: "B", Distance: 5, Capacity: 2}},
+		"B": {{To: "C", Distance: 4}},
+	}, g.Nodes())
+
+	assert.NoError(t, g.Import(strings.NewReader(`{"edges":[{"from":"C","to":"D","distance":8}]}`), FormatJSON))
+	assert.Equal(t, map[string][]Edge{"C": {{To: "D", Distance: 8}}}, g.Nodes())
+
+	assert.Error(t, g.Import(strings.NewReader(`{"from":"A"}`), FormatJSON))
+	assert.Error(t, g.Import(strings.NewReader(`["A5"]`), FormatJSON))
+
+	// other keys around the edges are allowed
+	assert.NoError(t, g.Import(strings.NewReader(`{"name":"S1","edges":["AB5"],"version":2}`), FormatJSON))
+
+	// a truncated or trailing document never replaces the graph
+	for _, body := range []string{`{"edges":["CD8"],`, `{"edges":["CD8"]`, `{"edges":["CD8"],"name"`, `["CD8"`, `["CD8"] ["DE1"]`, `{"edges":["CD8"]} x`} {
+		assert.Error(t, g.Import(strings.NewReader(body), FormatJSON), body)
+	}
+	assert.Equal(t, map[string][]Edge{"A": {{To: "B", Distance: 5}}}, g.Nodes())
+}
+
+func TestImportValidation(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB5"}))
+
+	// every format goes through the same checks, and a failed import keeps
+	// the current graph
+	cases := map[string]string{
+		`line 1, column 1: "A,A,5": self-loop not allowed`:                          "A,A,5",
+		`line 1, column 1: "A,B,0": invalid distance 0`:                             "A,B,0",
+		`line 2, column 1: "A,B,6": duplicate edge A->B, first at line 1, column 1`: "A,B,5\nA,B,6",
+		`line 1, column 1: "A1,B,5": invalid town name "A1"`:                        "A1,B,5",
+		`line 1, column 1: "A,B,5,-1": invalid capacity -1`:                         "A,B,5,-1",
+	}
+	for msg, body := range cases {
+		assert.EqualError(t, g.Import(strings.NewReader(body), FormatCSV), msg)
+	}
+	assert.Equal(t, map[string][]Edge{"A": {{To: "B", Distance: 5}}}, g.Nodes())
+
+	assert.EqualError(t, g.Import(strings.NewReader(""), "yaml"), `unsupported graph format: "yaml"`)
+}
+
+func TestRegisterImporter(t *testing.T) {
+	RegisterImporter("pairs", ImporterFunc(func(r io.Reader) ([]EdgeSpec, error) {
+		return []EdgeSpec{{From: "X", To: "Y", Distance: 1}}, nil
+	}), []string{".pairs"}, nil)
+	defer func() {
+		importersMu.Lock()
+		delete(importers, "pairs")
+		importersMu.Unlock()
+	}()
+
+	assert.Equal(t, "pairs", ImportFormat("net.pairs", ""))
+	g := NewGraph()
+	assert.NoError(t, g.Import(strings.NewReader(""), "pairs"))
+	assert.Equal(t, 1, g.NodeCount())
+}
@@ -0,0 +1,149 @@
+package graphs
+
+import (
+	"container/heap"
+)
+
+// AlgorithmALT is A* guided by landmark lower bounds (A*, landmarks and the
+// triangle inequality). It needs no coordinates and works on any graph.
+const AlgorithmALT Algorithm = "alt"
+
+// DefaultLandmarkCount is the number of landmarks chosen at load time.
+const DefaultLandmarkCount = 8
+
+// landmarkSet holds the exact distances from and to a few landmark towns.
+// By the triangle inequality, for any landmark L
+//
+//	d(v,t) >= d(L,t) - d(L,v)  and  d(v,t) >= d(v,L) - d(t,L)
+//
+// which gives a lower bound for every pair of towns. -1 marks a town that
+// is not connected to the landmark in that direction.
+type landmarkSet struct {
+	towns []int
+	from  [][]int // from[k][v] = d(towns[k], v)
+	to    [][]int // to[k][v] = d(v, towns[k])
+}
+
+// selectLandmarks picks up to k landmarks by farthest selection: the first
+// is the town farthest from town 0, each next one the town farthest from
+// all landmarks chosen so far, where towns not connected to a landmark
+// count as infinitely far.
+func selectLandmarks(c *csr, k int) *landmarkSet {
+	n := c.size()
+	if n == 0 || k <= 0 {
+		return nil
+	}
+	lm := &landmarkSet{}
+	// separation[v] is the smallest round trip from v to any landmark so
+	// far, -1 while v is not connected both ways to any of them
+	separation := make([]int, n)
+	for v := range separation {
+		separation[v] = -1
+	}
+	chosen := make([]bool, n)
+
+	first, _ := dijkstraFrom(c, 0, nil)
+	next := farthest(first, chosen)
+	for len(lm.towns) < k && next >= 0 {
+		from, _ := dijkstraFrom(c, next, nil)
+		to := dijkstraTo(c, next)
+		for v := range separation {
+			if from[v] < 0 || to[v] < 0 {
+				continue
+			}
+			if round := from[v] + to[v]; separation[v] < 0 || round < separation[v] {
+				separation[v] = round
+			}
+		}
+		chosen[next] = true
+		lm.towns = append(lm.towns, next)
+		lm.from = append(lm.from, from)
+		lm.to = append(lm.to, to)
+		next = farthest(separation, chosen)
+	}
+	return lm
+}
+
+// farthest returns the unchosen town with the largest value, preferring
+// -1 (infinitely far) and then the lowest id; -1 when all are chosen.
+func farthest(dist []int, chosen []bool) int {
+	best := -1
+	for v, d := range dist {
+		if chosen[v] {
+			continue
+		}
+		switch {
+		case best == -1:
+			best = v
+		case dist[best] < 0:
+		case d < 0 || d > dist[best]:
+			best = v
+		}
+	}
+	return best
+}
+
+// bound returns a lower bound on the distance from v to t. ok is false
+// when some landmark proves that t cannot be reached from v.
+func (lm *landmarkSet) bound(v, t int) (int, bool) {
+	best := 0
+	for k := range lm.towns {
+		fv, ft := lm.from[k][v], lm.from[k][t]
+		if fv >= 0 {
+			if ft < 0 {
+				return 0, false
+			}
+			best = max(best, ft-fv)
+		}
+		tv, tt := lm.to[k][v], lm.to[k][t]
+		if tt >= 0 {
+			if tv < 0 {
+				return 0, false
+			}
+			best = max(best, tv-tt)
+		}
+	}
+	return best, true
+}
+
+// heuristic returns the landmark bound towards t as an A* heuristic.
+func (lm *landmarkSet) heuristic(t int) func(int) int {
+	return func(v int) int {
+		b, _ := lm.bound(v, t)
+		return b
+	}
+}
+
+// dijkstraTo returns the shortest distance from every town to dst, -1 when
+// unreachable, by searching the incoming edges.
+func dijkstraTo(c *csr, dst int) []int {
+	dist := make([]int, c.size())
+	for i := range dist {
+		dist[i] = -1
+	}
+	pq := &priorityQueue{}
+	heap.Push(pq, pqItem{node: int32(dst), parent: -1})
+	for pq.Len() > 0 {
+		curr := heap.Pop(pq).(pqItem)
+		if dist[curr.node] >= 0 {
+			continue
+		}
+		dist[curr.node] = curr.dist
+		lo, hi := c.in(int(curr.node))
+		for i := lo; i < hi; i++ {
+			if dist[c.inSource[i]] < 0 {
+				heap.Push(pq, pqItem{node: c.inSource[i], dist: curr.dist + c.inDists[i]})
+			}
+		}
+	}
+	return dist
+}
+
+// Landmarks returns the towns chosen as landmarks for the current graph.
+func (g *Graph) Landmarks() []string {
+	c := g.snapshot()
+	if c.alt == nil {
+		return []string{}
+	}
+	return c.towns(c.alt.towns)
+}
@@ -0,0 +1,84 @@
+package graphs
+
+import (
+	"testing"
+
+	"github.com/aashi1008/hamburg-rails/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLandmarksSelection(t *testing.T) {
+	g := seedGraph()
+	// only five towns, so every town becomes a landmark
+	assert.ElementsMatch(t, []string{"A", "B", "C", "D", "E"}, g.Landmarks())
+
+	dense := denseGraph(t)
+	towns := dense.Landmarks()
+	assert.Len(t, towns, DefaultLandmarkCount)
+	seen := map[string]bool{}
+	for _, town := range towns {
+		assert.False(t, seen[town], "duplicate landmark %s", town)
+		seen[town] = true
+	}
+
+	assert.Empty(t, NewGraph().Landmarks())
+}
+
+func TestLandmarkBoundIsLowerBound(t *testing.T) {
+	for _, g := range []*Graph{seedGraph(), denseGraph(t), gridGraph(t)} {
+		c := g.snapshot()
+		for s := 0; s < c.size(); s++ {
+			dist, _ := dijkstraFrom(c, s, nil)
+			for v, d := range dist {
+				b, ok := c.alt.bound(s, v)
+				if d < 0 {
+					continue
+				}
+				assert.True(t, ok, "%s->%s is reachable", c.names[s], c.names[v])
+				assert.LessOrEqual(t, b, d, "%s->%s", c.names[s], c.names[v])
+			}
+		}
+	}
+}
+
+func TestLandmarkBoundUnreachable(t *testing.T) {
+	c := seedGraph().snapshot()
+	// no edge enters A
+	_, ok := c.alt.bound(c.ids["C"], c.ids["A"])
+	assert.False(t, ok)
+}
+
+func TestALTShortestPath(t *testing.T) {
+	g := denseGraph(t)
+	c := g.snapshot()
+	for _, from := range c.names {
+		for _, to := range c.names {
+			want, _, dijkstra := g.ShortestPathWith(from, to, AlgorithmDijkstra)
+			got, path, stats := g.ShortestPathWith(from, to, AlgorithmALT)
+			assert.Equal(t, AlgorithmALT, stats.Algorithm)
+			assert.Equal(t, want, got, "%s->%s", from, to)
+			if got != -1 {
+				d, err := g.Distance(path)
+				assert.NoError(t, err)
+				assert.Equal(t, got, d)
+				assert.LessOrEqual(t, stats.NodesExpanded, dijkstra.NodesExpanded)
+			}
+		}
+	}
+
+	dist, path, _ := seedGraph().ShortestPathWith("C", "C", AlgorithmALT)
+	assert.Equal(t, 9, dist)
+	assert.Equal(t, []string{"C", "E", "B", "C"}, path)
+}
+
+func TestSearchRoutesLandmarkPruning(t *testing.T) {
+	g := denseGraph(t)
+	req := models.RouteSearchRequest{Constraints: models.RouteSearchConstraints{MaxDistance: 40}}
+	pruned := g.SearchRoutes("A", "Z", req)
+
+	// the same search without landmarks
+	g.data.alt = nil
+	full := g.SearchRoutes("A", "Z", req)
+	assert.NotEmpty(t, full.Routes)
+	assert.Equal(t, full, pruned)
+}
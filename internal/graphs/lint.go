@@ -0,0 +1,142 @@
+package graphs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Checks run by a dry-run load, see LintWarning.
+const (
+	LintUnreachable = "unreachable"
+	LintSink        = "sink"
+	LintTriangle    = "triangle-inequality"
+	LintLongEdge    = "long-edge"
+	LintComponent   = "isolated-component"
+)
+
+// lintLongEdgeFactor is how many times the median distance an edge must be
+// to be reported as unusually long.
+const lintLongEdgeFactor = 10
+
+// LintWarning is something likely to be a mistake in a valid graph. Check
+// is one of the Lint* names above and Towns are the towns concerned.
+type LintWarning struct {
+	Check   string   `json:"check"`
+	Towns   []string `json:"towns"`
+	Message string   `json:"message"`
+}
+
+// lint checks a graph for likely mistakes and returns the first
+// maxImportWarnings warnings along with how many there are:
+//
+//   - towns no edge leads to, which no trip can reach
+//   - sinks, towns no edge leaves, where every trip ends
+//   - edges longer than a detour through one other town
+//   - edges more than lintLongEdgeFactor times the median distance
+//   - groups of towns not connected to the largest group in either direction
+func lint(c *csr) ([]LintWarning, int) {
+	var warnings []LintWarning
+	warn := func(check string, towns []string, format string, args ...interface{}) {
+		warnings = append(warnings, LintWarning{Check: check, Towns: towns, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for v, name := range c.names {
+		if lo, hi := c.in(v); lo == hi {
+			warn(LintUnreachable, []string{name}, "no edge leads to %s", name)
+		}
+		if lo, hi := c.out(v); lo == hi {
+			warn(LintSink, []string{name}, "no edge leaves %s", name)
+		}
+	}
+
+	for u := range c.names {
+		lo, hi := c.out(u)
+		for i := lo; i < hi; i++ {
+			v := int(c.targets[i])
+			// the shortest detour u->w->v
+			best, via := c.dists[i], -1
+			for j := lo; j < hi; j++ {
+				w := int(c.targets[j])
+				if w == v {
+					continue
+				}
+				if k, ok := c.find(w, v); ok && c.dists[j]+c.dists[k] < best {
+					best, via = c.dists[j]+c.dists[k], w
+				}
+			}
+			if via >= 0 {
+				warn(LintTriangle, c.towns([]int{u, via, v}), "%s->%s (%s) is longer than %s->%s->%s (%s)",
+					c.names[u], c.names[v], formatDistance(c.dists[i], c.scale), c.names[u], c.names[via], c.names[v], formatDistance(best, c.scale))
+			}
+		}
+	}
+
+	if len(c.dists) > 0 {
+		sorted := append([]int(nil), c.dists...)
+		sort.Ints(sorted)
+		median := sorted[len(sorted)/2]
+		for u := range c.names {
+			lo, hi := c.out(u)
+			for i := lo; i < hi; i++ {
+				if c.dists[i] > lintLongEdgeFactor*median {
+					v := int(c.targets[i])
+					warn(LintLongEdge, c.towns([]int{u, v}), "%s->%s (%s) is more than %d times the median distance %s",
+						c.names[u], c.names[v], formatDistance(c.dists[i], c.scale), lintLongEdgeFactor, formatDistance(median, c.scale))
+				}
+			}
+		}
+	}
+
+	components := weakComponents(c)
+	for _, comp := range components[min(1, len(components)):] {
+		towns := c.towns(comp)
+		warn(LintComponent, towns, "%s not connected to the rest of the network", strings.Join(towns, ", "))
+	}
+
+	count := len(warnings)
+	if count > maxImportWarnings {
+		warnings = warnings[:maxImportWarnings]
+	}
+	return warnings, count
+}
+
+// weakComponents returns the towns of each group connected ignoring edge
+// direction, largest first and then by first town.
+func weakComponents(c *csr) [][]int {
+	comp := make([]int, c.size())
+	for v := range comp {
+		comp[v] = -1
+	}
+	var components [][]int
+	for start := range c.names {
+		if comp[start] >= 0 {
+			continue
+		}
+		id := len(components)
+		comp[start] = id
+		members := []int{start}
+		for k := 0; k < len(members); k++ {
+			v := members[k]
+			lo, hi := c.out(v)
+			inLo, inHi := c.in(v)
+			next := make([]int, 0, hi-lo+inHi-inLo)
+			for i := lo; i < hi; i++ {
+				next = append(next, int(c.targets[i]))
+			}
+			for i := inLo; i < inHi; i++ {
+				next = append(next, int(c.inSource[i]))
+			}
+			for _, w := range next {
+				if comp[w] < 0 {
+					comp[w] = id
+					members = append(members, w)
+				}
+			}
+		}
+		sort.Ints(members)
+		components = append(components, members)
+	}
+	sort.SliceStable(components, func(i, j int) bool { return len(components[i]) > len(components[j]) })
+	return components
+}
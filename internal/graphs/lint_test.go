@@ -0,0 +1,78 @@
+package graphs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lintChecks(warnings []LintWarning) map[string][]string {
+	checks := map[string][]string{}
+	for _, w := range warnings {
+		checks[w.Check] = append(checks[w.Check], strings.Join(w.Towns, " "))
+	}
+	return checks
+}
+
+func TestLint(t *testing.T) {
+	edges := []rawEdge{
+		{from: "A", to: "B", distance: 2}, {from: "B", to: "A", distance: 2},
+		{from: "B", to: "C", distance: 3}, {from: "C", to: "B", distance: 3},
+		{from: "A", to: "C", distance: 9}, {from: "C", to: "A", distance: 4},
+		{from: "C", to: "D", distance: 200},
+		{from: "E", to: "A", distance: 3},
+		{from: "X", to: "Y", distance: 1}, {from: "Y", to: "X", distance: 1},
+	}
+	warnings, count := lint(newCSR(edges))
+	assert.Equal(t, len(warnings), count)
+	assert.Equal(t, map[string][]string{
+		LintUnreachable: {"E"},
+		LintSink:        {"D"},
+		LintTriangle:    {"A B C"},
+		LintLongEdge:    {"C D"},
+		LintComponent:   {"X Y"},
+	}, lintChecks(warnings))
+	for _, w := range warnings {
+		switch w.Check {
+		case LintTriangle:
+			assert.Equal(t, "A->C (9) is longer than A->B->C (5)", w.Message)
+		case LintLongEdge:
+			assert.Equal(t, "C->D (200) is more than 10 times the median distance 3", w.Message)
+		case LintComponent:
+			assert.Equal(t, "X, Y not connected to the rest of the network", w.Message)
+		}
+	}
+
+	warnings, count = lint(newCSR(nil))
+	assert.Empty(t, warnings)
+	assert.Equal(t, 0, count)
+}
+
+func TestWeakComponents(t *testing.T) {
+	c := newCSR([]rawEdge{
+		{from: "A", to: "B", distance: 1},
+		{from: "C", to: "D", distance: 1}, {from: "E", to: "D", distance: 1},
+		{from: "F", to: "G", distance: 1},
+	})
+	assert.Equal(t, [][]int{{2, 3, 4}, {0, 1}, {5, 6}}, weakComponents(c))
+}
+
+func TestImportDryRun(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB5"}))
+
+	report, err := g.ImportWithOptions(strings.NewReader("XY1, YX1, YZ2"), FormatText, ImportOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Z"}, lintChecks(report.Lint)[LintSink])
+	assert.Equal(t, 1, report.AsymmetricCount)
+	assert.Equal(t, []EdgeSpec{{From: "A", To: "B", Distance: 5}}, g.EdgeList())
+
+	// errors are reported along with the report on the valid edges
+	report, err = g.ImportWithOptions(strings.NewReader("XY1, YY1, YZ2"), FormatText, ImportOptions{DryRun: true})
+	assert.True(t, errors.Is(err, ErrSelfLoop))
+	assert.Equal(t, 2, report.AsymmetricCount)
+	assert.NotEmpty(t, report.Lint)
+	assert.Equal(t, []EdgeSpec{{From: "A", To: "B", Distance: 5}}, g.EdgeList())
+}
@@ -0,0 +1,77 @@
+package graphs
+
+import (
+	"sort"
+)
+
+// EdgeFlow reports the flow carried by a single edge.
+type EdgeFlow struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Capacity int    `json:"capacity"`
+	Flow     int    `json:"flow"`
+}
+
+// MaxFlowResult is the outcome of a max-flow/min-cut analysis.
+type MaxFlowResult struct {
+	Value int        `json:"flow"`
+	Edges []EdgeFlow `json:"edges"`
+	// MinCut lists the saturated edges separating the towns still reachable
+	// from the origin in the residual network from the rest.
+	MinCut []EdgeFlow `json:"minCut"`
+}
+
+// MaxFlow computes the maximum number of trains per hour that can travel
+// from one town to another given edge capacities, together with the edges
+// carrying flow and a minimum cut. Edges without capacity count as
+// DefaultCapacity. ok is false when either town is not in the graph.
+func (g *Graph) MaxFlow(from, to string) (res MaxFlowResult, ok bool) {
+	res = MaxFlowResult{Edges: []EdgeFlow{}, MinCut: []EdgeFlow{}}
+	c := g.snapshot()
+	s, okFrom := c.id(from)
+	t, okTo := c.id(to)
+	if !okFrom || !okTo {
+		return res, false
+	}
+	if s == t {
+		return res, true
+	}
+
+	net := newFlowNetwork(c.size())
+	// arc[i] is the flow arc of edge slot i
+	arc := make([]int, len(c.targets))
+	for u := 0; u < c.size(); u++ {
+		lo, hi := c.out(u)
+		for i := lo; i < hi; i++ {
+			arc[i] = net.addArc(u, int(c.targets[i]), c.capacity(i), 0)
+		}
+	}
+	res.Value = net.maxFlow(s, t)
+
+	side := net.reachable(s)
+	for u := 0; u < c.size(); u++ {
+		lo, hi := c.out(u)
+		for i := lo; i < hi; i++ {
+			w := int(c.targets[i])
+			ef := EdgeFlow{From: c.names[u], To: c.names[w], Capacity: c.capacity(i), Flow: net.arcs[arc[i]].flow}
+			if ef.Flow > 0 {
+				res.Edges = append(res.Edges, ef)
+			}
+			if side[u] && !side[w] {
+				res.MinCut = append(res.MinCut, ef)
+			}
+		}
+	}
+	sortEdgeFlows(res.Edges)
+	sortEdgeFlows(res.MinCut)
+	return res, true
+}
+
+func sortEdgeFlows(flows []EdgeFlow) {
+	sort.Slice(flows, func(i, j int) bool {
+		if flows[i].From != flows[j].From {
+			return flows[i].From < flows[j].From
+		}
+		return flows[i].To < flows[j].To
+	})
+}
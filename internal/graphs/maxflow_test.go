@@ -0,0 +1,67 @@
+package graphs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxFlowWithCapacities(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"SA10/3", "SB10/2", "AB1/1", "AT10/2", "BT10/3"}))
+
+	res, ok := g.MaxFlow("S", "T")
+	assert.True(t, ok)
+	assert.Equal(t, 5, res.Value)
+	assert.Equal(t, []EdgeFlow{
+		{From: "S", To: "A", Capacity: 3, Flow: 3},
+		{From: "S", To: "B", Capacity: 2, Flow: 2},
+	}, res.MinCut)
+
+	// flow is conserved at every intermediate town
+	balance := map[string]int{}
+	for _, e := range res.Edges {
+		assert.LessOrEqual(t, e.Flow, e.Capacity)
+		balance[e.From] -= e.Flow
+		balance[e.To] += e.Flow
+	}
+	assert.Equal(t, 0, balance["A"])
+	assert.Equal(t, 0, balance["B"])
+	assert.Equal(t, 5, balance["T"])
+}
+
+func TestMaxFlowDefaultCapacity(t *testing.T) {
+	g := seedGraph()
+
+	// without capacities the flow counts edge-disjoint routes
+	res, ok := g.MaxFlow("A", "C")
+	assert.True(t, ok)
+	assert.Equal(t, 2, res.Value)
+	assert.Len(t, res.MinCut, 2)
+
+	res, ok = g.MaxFlow("C", "A")
+	assert.True(t, ok)
+	assert.Equal(t, 0, res.Value)
+	assert.Empty(t, res.Edges)
+}
+
+func TestMaxFlowUnknownTown(t *testing.T) {
+	g := seedGraph()
+
+	_, ok := g.MaxFlow("A", "Z")
+	assert.False(t, ok)
+	_, ok = g.MaxFlow("Z", "A")
+	assert.False(t, ok)
+}
+
+func TestLoadEdgesCapacity(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB5/12", "BC4"}))
+	assert.Equal(t, Edge{To: "B", Distance: 5, Capacity: 12}, g.Nodes()["A"][0])
+	assert.Equal(t, 0, g.Nodes()["B"][0].Capacity)
+	res, _ := g.MaxFlow("B", "C")
+	assert.Equal(t, DefaultCapacity, res.Value)
+
+	assert.Error(t, g.LoadEdges([]string{"AB5/0"}))
+	assert.Error(t, g.LoadEdges([]string{"AB5/"}))
+}
@@ -0,0 +1,374 @@
+package graphs
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// osmSnapKm is how far a station node may be from the nearest track node it
+// is attached to.
+const osmSnapKm = 1.0
+
+// osmGridDegrees is the cell size of the grid used to find the track node
+// nearest a station; at least osmSnapKm at any latitude served by rail.
+const osmGridDegrees = 0.05
+
+type osmStation struct {
+	id   int64
+	name string
+	pos  Coordinate
+}
+
+// osmData is what the OSM importers keep of an extract: the stations, the
+// rail ways as lists of node ids and the positions of the nodes on them.
+// Extracts list the nodes before the ways, so it is filled in two passes:
+// the first keeps the stations and ways, the second, once track is set,
+// only the positions of the track nodes.
+type osmData struct {
+	coords   map[int64]Coordinate
+	stations []osmStation
+	ways     [][]int64
+	track    map[int64]bool
+	// unplaced holds the nodes already reported without a valid position,
+	// so that a station on the track is reported once over both passes.
+	unplaced map[int64]bool
+	importWarnings
+}
+
+func newOSMData() *osmData {
+	return &osmData{coords: map[int64]Coordinate{}, unplaced: map[int64]bool{}}
+}
+
+// wants reports whether the current pass keeps the node.
+func (d *osmData) wants(id int64, tag func(string) string) bool {
+	if d.track != nil {
+		return d.track[id]
+	}
+	return tag != nil && tag("railway") == "station"
+}
+
+// unplacedNode reports a node without a valid position if the current pass
+// keeps it and no pass has reported it yet.
+func (d *osmData) unplacedNode(id int64, line int, tag func(string) string) {
+	if d.wants(id, tag) && !d.unplaced[id] {
+		d.unplaced[id] = true
+		d.warn("", line, "node %d has no valid position", id)
+	}
+}
+
+// node records a node; tag looks up its tags.
+func (d *osmData) node(id int64, c Coordinate, tag func(string) string) {
+	if !d.wants(id, tag) {
+		return
+	}
+	if d.track != nil {
+		d.coords[id] = c
+		return
+	}
+	d.stations = append(d.stations, osmStation{id: id, name: tag("name"), pos: c})
+}
+
+func (d *osmData) way(refs []int64, tag func(string) string) {
+	if d.track == nil && tag != nil && tag("railway") == "rail" && len(refs) > 1 {
+		d.ways = append(d.ways, refs)
+	}
+}
+
+// trackPass switches to the second pass, keeping only the nodes of the ways.
+func (d *osmData) trackPass() {
+	d.track = map[int64]bool{}
+	for _, refs := range d.ways {
+		for _, ref := range refs {
+			d.track[ref] = true
+		}
+	}
+}
+
+// osmImporter reads OSM XML, or with pbf set the PBF format, and builds the
+// graph between the railway=station nodes along the railway=rail ways. Each
+// station is attached to its nearest track node, and is joined to every
+// station it reaches along the tracks without passing another, with the
+// track length in kilometres to the metre.
+type osmImporter struct {
+	pbf bool
+}
+
+func (o osmImporter) Import(r io.Reader) ([]EdgeSpec, error) {
+	res, err := o.ImportResult(r, ImportOptions{})
+	return res.Edges, err
+}
+
+func (o osmImporter) ImportResult(r io.Reader, _ ImportOptions) (ImportResult, error) {
+	// files and uploads can be read again; anything else is buffered
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return ImportResult{}, err
+		}
+		rs = bytes.NewReader(data)
+	}
+	read := readOSMXML
+	if o.pbf {
+		read = readOSMPBF
+	}
+	d := newOSMData()
+	if err := read(rs, d); err != nil {
+		return ImportResult{}, err
+	}
+	d.trackPass()
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return ImportResult{}, err
+	}
+	if err := read(rs, d); err != nil {
+		return ImportResult{}, err
+	}
+	d.track = nil
+	specs := d.build()
+	return ImportResult{Edges: specs, Metadata: Metadata{Units: "km"}, Warnings: d.warnings()}, nil
+}
+
+type osmTag struct {
+	K string `xml:"k,attr"`
+	V string `xml:"v,attr"`
+}
+
+func osmTags(tags []osmTag) func(string) string {
+	if len(tags) == 0 {
+		return nil
+	}
+	return func(key string) string {
+		for _, t := range tags {
+			if t.K == key {
+				return t.V
+			}
+		}
+		return ""
+	}
+}
+
+// readOSMXML streams the nodes and ways of an OSM XML document.
+func readOSMXML(r io.Reader, d *osmData) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid osm xml: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		line, _ := dec.InputPos()
+		switch start.Name.Local {
+		case "node":
+			var n struct {
+				ID   int64    `xml:"id,attr"`
+				Lat  string   `xml:"lat,attr"`
+				Lon  string   `xml:"lon,attr"`
+				Tags []osmTag `xml:"tag"`
+			}
+			if err := dec.DecodeElement(&n, &start); err != nil {
+				return fmt.Errorf("invalid osm xml: %v", err)
+			}
+			lat, errLat := strconv.ParseFloat(n.Lat, 64)
+			lon, errLon := strconv.ParseFloat(n.Lon, 64)
+			if errLat != nil || errLon != nil {
+				d.unplacedNode(n.ID, line, osmTags(n.Tags))
+				continue
+			}
+			d.node(n.ID, Coordinate{Lat: lat, Lon: lon}, osmTags(n.Tags))
+		case "way":
+			var w struct {
+				Refs []struct {
+					Ref int64 `xml:"ref,attr"`
+				} `xml:"nd"`
+				Tags []osmTag `xml:"tag"`
+			}
+			if err := dec.DecodeElement(&w, &start); err != nil {
+				return fmt.Errorf("invalid osm xml: %v", err)
+			}
+			refs := make([]int64, len(w.Refs))
+			for i, nd := range w.Refs {
+				refs[i] = nd.Ref
+			}
+			d.way(refs, osmTags(w.Tags))
+		}
+	}
+}
+
+type osmCell struct{ lat, lon int }
+
+func osmCellOf(c Coordinate) osmCell {
+	return osmCell{int(math.Floor(c.Lat / osmGridDegrees)), int(math.Floor(c.Lon / osmGridDegrees))}
+}
+
+type osmArc struct {
+	to int
+	km float64
+}
+
+// build joins the stations along the tracks.
+func (d *osmData) build() []EdgeSpec {
+	// track nodes are interned to dense indices in way order
+	index := map[int64]int{}
+	var pos []Coordinate
+	var adj [][]osmArc
+	intern := func(id int64) (int, bool) {
+		if i, ok := index[id]; ok {
+			return i, true
+		}
+		c, ok := d.coords[id]
+		if !ok {
+			return 0, false
+		}
+		index[id] = len(pos)
+		pos = append(pos, c)
+		adj = append(adj, nil)
+		return len(pos) - 1, true
+	}
+	for _, refs := range d.ways {
+		prev := -1
+		for _, ref := range refs {
+			i, ok := intern(ref)
+			if !ok {
+				d.warn("", 0, "way refers to missing node %d", ref)
+				prev = -1
+				continue
+			}
+			if prev >= 0 && prev != i {
+				km := greatCircleKm(pos[prev], pos[i])
+				adj[prev] = append(adj[prev], osmArc{i, km})
+				adj[i] = append(adj[i], osmArc{prev, km})
+			}
+			prev = i
+		}
+	}
+
+	grid := map[osmCell][]int{}
+	for i, c := range pos {
+		cell := osmCellOf(c)
+		grid[cell] = append(grid[cell], i)
+	}
+
+	// attach and name the stations, in id order
+	sort.Slice(d.stations, func(i, j int) bool { return d.stations[i].id < d.stations[j].id })
+	at := map[int][]string{}
+	attached := map[string]int{}
+	namer := newTownNamer()
+	for _, s := range d.stations {
+		c := s.pos
+		node, ok := index[s.id]
+		if !ok {
+			node = -1
+			best := osmSnapKm
+			home := osmCellOf(c)
+			for dl := -1; dl <= 1; dl++ {
+				for dn := -1; dn <= 1; dn++ {
+					for _, i := range grid[osmCell{home.lat + dl, home.lon + dn}] {
+						if km := greatCircleKm(c, pos[i]); km <= best {
+							node, best = i, km
+						}
+					}
+				}
+			}
+		}
+		if node < 0 {
+			d.warn("", 0, "station %d (%s) is not within %g km of a track", s.id, s.name, osmSnapKm)
+			continue
+		}
+		town, base, ok := namer.name(s.name)
+		switch {
+		case base == "":
+			d.warn("", 0, "station %d has no usable name", s.id)
+			continue
+		case !ok:
+			d.warn("", 0, "station %d has the same name as too many others", s.id)
+			continue
+		case town != base:
+			d.warn("", 0, "station %d renamed %s, %s is taken", s.id, town, base)
+		}
+		at[node] = append(at[node], town)
+		attached[town] = node
+	}
+
+	towns := make([]string, 0, len(attached))
+	for t := range attached {
+		towns = append(towns, t)
+	}
+	sort.Strings(towns)
+	var specs []EdgeSpec
+	for _, from := range towns {
+		for _, hit := range osmNeighbours(adj, at, attached[from], from) {
+			dist, scale := measuredDistance(hit.km)
+			specs = append(specs, EdgeSpec{From: from, To: hit.town, Distance: dist, Scale: scale})
+		}
+	}
+	return specs
+}
+
+type osmHit struct {
+	town string
+	km   float64
+}
+
+// osmNeighbours runs Dijkstra along the tracks from src, stopping at every
+// track node with a station, and returns the stations found other than self
+// in order of distance.
+func osmNeighbours(adj [][]osmArc, at map[int][]string, src int, self string) []osmHit {
+	dist := map[int]float64{}
+	pq := &osmQueue{{node: src}}
+	var hits []osmHit
+	for pq.Len() > 0 {
+		curr := heap.Pop(pq).(osmItem)
+		if _, done := dist[curr.node]; done {
+			continue
+		}
+		dist[curr.node] = curr.km
+		if towns := at[curr.node]; len(towns) > 0 {
+			for _, t := range towns {
+				if t != self {
+					hits = append(hits, osmHit{t, curr.km})
+				}
+			}
+			if curr.node != src {
+				continue
+			}
+		}
+		for _, a := range adj[curr.node] {
+			if _, done := dist[a.to]; !done {
+				heap.Push(pq, osmItem{node: a.to, km: curr.km + a.km})
+			}
+		}
+	}
+	return hits
+}
+
+type osmItem struct {
+	node int
+	km   float64
+}
+
+type osmQueue []osmItem
+
+func (q osmQueue) Len() int            { return len(q) }
+func (q osmQueue) Less(i, j int) bool  { return q[i].km < q[j].km }
+func (q osmQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *osmQueue) Push(x interface{}) { *q = append(*q, x.(osmItem)) }
+func (q *osmQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
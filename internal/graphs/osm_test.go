@@ -0,0 +1,116 @@
+package graphs
+
+import (
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const osmExtract = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6">
+  <node id="1" lat="53.55" lon="10.00">
+    <tag k="railway" v="station"/>
+    <tag k="name" v="Hamburg Hbf"/>
+  </node>
+  <node id="2" lat="53.55" lon="10.05"/>
+  <node id="3" lat="53.55" lon="10.10"/>
+  <node id="4" lat="53.55" lon="10.20"/>
+  <node id="5" lat="53.55" lon="10.30">
+    <tag k="railway" v="station"/>
+    <tag k="name" v="Bergedorf"/>
+  </node>
+  <node id="10" lat="53.553" lon="10.10">
+    <tag k="railway" v="station"/>
+    <tag k="name" v="Tiefstack"/>
+  </node>
+  <node id="11" lat="53.70" lon="10.10">
+    <tag k="railway" v="station"/>
+    <tag k="name" v="Far Away"/>
+  </node>
+  <node id="12" lat="53.555" lon="10.00">
+    <tag k="railway" v="station"/>
+  </node>
+  <way id="100">
+    <nd ref="1"/><nd ref="2"/><nd ref="3"/>
+    <tag k="railway" v="rail"/>
+  </way>
+  <way id="101">
+    <nd ref="3"/><nd ref="4"/><nd ref="5"/><nd ref="99"/>
+    <tag k="railway" v="rail"/>
+  </way>
+  <way id="102">
+    <nd ref="1"/><nd ref="5"/>
+    <tag k="highway" v="primary"/>
+  </way>
+</osm>`
+
+func TestImportOSM(t *testing.T) {
+	g := NewGraph()
+	warnings, err := g.ImportWithWarnings(strings.NewReader(osmExtract), FormatOSM)
+	assert.NoError(t, err)
+
+	at := func(lon float64) Coordinate { return Coordinate{Lat: 53.55, Lon: lon} }
+	// Tiefstack is attached to node 3, and Hamburg and Bergedorf only
+	// reach each other through it
+	toTiefstack := int(math.Round(1000 * (greatCircleKm(at(10.00), at(10.05)) + greatCircleKm(at(10.05), at(10.10)))))
+	toBergedorf := int(math.Round(1000 * (greatCircleKm(at(10.10), at(10.20)) + greatCircleKm(at(10.20), at(10.30)))))
+	assert.Equal(t, map[string][]Edge{
+		"HAMBURGHBF": {{To: "TIEFSTACK", Distance: toTiefstack}},
+		"TIEFSTACK":  {{To: "HAMBURGHBF", Distance: toTiefstack}, {To: "BERGEDORF", Distance: toBergedorf}},
+		"BERGEDORF":  {{To: "TIEFSTACK", Distance: toBergedorf}},
+	}, g.Nodes())
+	// track lengths are kept to the metre
+	assert.Equal(t, 1000, g.DistanceScale())
+	assert.Equal(t, []ImportWarning{
+		{Message: "way refers to missing node 99"},
+		{Message: "station 11 (Far Away) is not within 1 km of a track"},
+		{Message: "station 12 has no usable name"},
+	}, warnings)
+}
+
+func TestImportOSMWarnsOncePerNode(t *testing.T) {
+	// node 2 is a station on the track, so both passes keep it
+	extract := `<osm>
+  <node id="1" lat="53.55" lon="10.00"><tag k="railway" v="station"/><tag k="name" v="West"/></node>
+  <node id="2" lat="x" lon="10.05"><tag k="railway" v="station"/><tag k="name" v="Middle"/></node>
+  <node id="3" lat="53.55" lon="10.10"><tag k="railway" v="station"/><tag k="name" v="East"/></node>
+  <node id="4" lat="" lon=""/>
+  <way id="100"><nd ref="1"/><nd ref="2"/><nd ref="3"/><tag k="railway" v="rail"/></way>
+</osm>`
+	warnings, err := NewGraph().ImportWithWarnings(strings.NewReader(extract), FormatOSM)
+	assert.NoError(t, err)
+	assert.Equal(t, []ImportWarning{
+		{Line: 3, Message: "node 2 has no valid position"},
+		{Message: "way refers to missing node 2"},
+	}, warnings)
+}
+
+func TestImportOSMFormats(t *testing.T) {
+	assert.Equal(t, FormatOSM, ImportFormat("hamburg.osm", ""))
+	assert.Equal(t, FormatOSMPBF, ImportFormat("hamburg-latest.osm.pbf", ""))
+
+	g := NewGraph()
+	assert.Error(t, g.Import(strings.NewReader("<osm><node"), FormatOSM))
+}
+
+func TestImportOSMKeepsOnlyTrackNodes(t *testing.T) {
+	d := newOSMData()
+	assert.NoError(t, readOSMXML(strings.NewReader(osmExtract), d))
+	assert.Empty(t, d.coords)
+	assert.Len(t, d.stations, 5)
+	d.trackPass()
+	assert.NoError(t, readOSMXML(strings.NewReader(osmExtract), d))
+	// stations 1 and 5 are on the tracks, 10 to 12 and the highway are not
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, slices.Sorted(maps.Keys(d.coords)))
+
+	// readers that cannot seek are buffered for the second pass
+	seekable, streamed := NewGraph(), NewGraph()
+	assert.NoError(t, seekable.Import(strings.NewReader(osmExtract), FormatOSM))
+	assert.NoError(t, streamed.Import(io.MultiReader(strings.NewReader(osmExtract)), FormatOSM))
+	assert.Equal(t, seekable.Nodes(), streamed.Nodes())
+}
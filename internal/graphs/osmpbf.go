@@ -0,0 +1,366 @@
+package graphs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// maxPBFBlobSize is the largest blob the OSM PBF format allows.
+const maxPBFBlobSize = 32 << 20
+
+// pbfFeatures are the OSMHeader required features readOSMPBF understands.
+var pbfFeatures = map[string]bool{"OsmSchema-V0.6": true, "DenseNodes": true}
+
+// pbfField is one field of a protobuf message. Varint and fixed fields are
+// in num, length-delimited ones in raw.
+type pbfField struct {
+	num protowire.Number
+	typ protowire.Type
+	val uint64
+	raw []byte
+}
+
+// pbfFields calls fn with each field of a protobuf message.
+func pbfFields(msg []byte, fn func(f pbfField) error) error {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		f := pbfField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.val, n = protowire.ConsumeVarint(msg)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(msg)
+			f.val = uint64(v)
+		case protowire.Fixed64Type:
+			f.val, n = protowire.ConsumeFixed64(msg)
+		case protowire.BytesType:
+			f.raw, n = protowire.ConsumeBytes(msg)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, msg)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// varints returns the values of a repeated varint field, packed or not.
+func (f pbfField) varints() ([]uint64, error) {
+	if f.typ == protowire.VarintType {
+		return []uint64{f.val}, nil
+	}
+	var out []uint64
+	for b := f.raw; len(b) > 0; {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		out = append(out, v)
+		b = b[n:]
+	}
+	return out, nil
+}
+
+// deltas decodes a packed sint64 field of delta-coded values.
+func (f pbfField) deltas() ([]int64, error) {
+	raw, err := f.varints()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int64, len(raw))
+	var acc int64
+	for i, v := range raw {
+		acc += protowire.DecodeZigZag(v)
+		out[i] = acc
+	}
+	return out, nil
+}
+
+// readOSMPBF reads the nodes and ways of an OSM PBF file: a sequence of
+// length-prefixed BlobHeaders, each followed by a Blob holding an OSMHeader
+// or a PrimitiveBlock, raw or zlib-compressed.
+func readOSMPBF(r io.Reader, d *osmData) error {
+	var size [4]byte
+	for {
+		if _, err := io.ReadFull(r, size[:]); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid osm pbf: %v", err)
+		}
+		headerSize := binary.BigEndian.Uint32(size[:])
+		if headerSize > 64<<10 {
+			return fmt.Errorf("invalid osm pbf: blob header of %d bytes", headerSize)
+		}
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return fmt.Errorf("invalid osm pbf: %v", err)
+		}
+		var kind string
+		var dataSize uint64
+		err := pbfFields(header, func(f pbfField) error {
+			switch f.num {
+			case 1:
+				kind = string(f.raw)
+			case 3:
+				dataSize = f.val
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("invalid osm pbf: %v", err)
+		}
+		if dataSize > maxPBFBlobSize {
+			return fmt.Errorf("invalid osm pbf: blob of %d bytes", dataSize)
+		}
+		blob := make([]byte, dataSize)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return fmt.Errorf("invalid osm pbf: %v", err)
+		}
+		data, err := pbfBlobData(blob)
+		if err != nil {
+			return fmt.Errorf("invalid osm pbf: %v", err)
+		}
+		switch kind {
+		case "OSMHeader":
+			err = pbfHeader(data)
+		case "OSMData":
+			err = pbfPrimitiveBlock(data, d)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid osm pbf: %v", err)
+		}
+	}
+}
+
+// pbfBlobData returns the uncompressed content of a Blob.
+func pbfBlobData(blob []byte) ([]byte, error) {
+	var raw, compressed []byte
+	var rawSize uint64
+	unsupported := ""
+	err := pbfFields(blob, func(f pbfField) error {
+		switch f.num {
+		case 1:
+			raw = f.raw
+		case 2:
+			rawSize = f.val
+		case 3:
+			compressed = f.raw
+		case 4, 5, 6, 7:
+			unsupported = map[protowire.Number]string{4: "lzma", 5: "bzip2", 6: "lz4", 7: "zstd"}[f.num]
+		}
+		return nil
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case raw != nil:
+		return raw, nil
+	case compressed != nil:
+		if rawSize > maxPBFBlobSize {
+			return nil, fmt.Errorf("blob of %d bytes", rawSize)
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(io.LimitReader(zr, maxPBFBlobSize))
+	case unsupported != "":
+		return nil, fmt.Errorf("%s compression is not supported", unsupported)
+	}
+	return nil, nil
+}
+
+func pbfHeader(data []byte) error {
+	return pbfFields(data, func(f pbfField) error {
+		if f.num == 4 && !pbfFeatures[string(f.raw)] {
+			return fmt.Errorf("required feature %q is not supported", f.raw)
+		}
+		return nil
+	})
+}
+
+// pbfBlock holds the PrimitiveBlock fields needed to decode its groups.
+type pbfBlock struct {
+	strings     [][]byte
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func (b *pbfBlock) coordinate(lat, lon int64) Coordinate {
+	return Coordinate{
+		Lat: 1e-9 * float64(b.latOffset+b.granularity*lat),
+		Lon: 1e-9 * float64(b.lonOffset+b.granularity*lon),
+	}
+}
+
+func (b *pbfBlock) str(i uint64) string {
+	if i < uint64(len(b.strings)) {
+		return string(b.strings[i])
+	}
+	return ""
+}
+
+// tags looks tags up in parallel key and value string table indices.
+func (b *pbfBlock) tags(keys, vals []uint64) func(string) string {
+	if len(keys) == 0 {
+		return nil
+	}
+	return func(key string) string {
+		for i, k := range keys {
+			if b.str(k) == key && i < len(vals) {
+				return b.str(vals[i])
+			}
+		}
+		return ""
+	}
+}
+
+func pbfPrimitiveBlock(data []byte, d *osmData) error {
+	b := &pbfBlock{granularity: 100}
+	var groups [][]byte
+	err := pbfFields(data, func(f pbfField) error {
+		switch f.num {
+		case 1:
+			return pbfFields(f.raw, func(s pbfField) error {
+				if s.num == 1 {
+					b.strings = append(b.strings, s.raw)
+				}
+				return nil
+			})
+		case 2:
+			groups = append(groups, f.raw)
+		case 17:
+			b.granularity = int64(f.val)
+		case 19:
+			b.latOffset = int64(f.val)
+		case 20:
+			b.lonOffset = int64(f.val)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		err := pbfFields(g, func(f pbfField) error {
+			switch f.num {
+			case 1:
+				return b.node(f.raw, d)
+			case 2:
+				return b.denseNodes(f.raw, d)
+			case 3:
+				return b.way(f.raw, d)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *pbfBlock) node(msg []byte, d *osmData) error {
+	var id, lat, lon int64
+	var keys, vals []uint64
+	err := pbfFields(msg, func(f pbfField) error {
+		var err error
+		switch f.num {
+		case 1:
+			id = protowire.DecodeZigZag(f.val)
+		case 2:
+			keys, err = f.varints()
+		case 3:
+			vals, err = f.varints()
+		case 8:
+			lat = protowire.DecodeZigZag(f.val)
+		case 9:
+			lon = protowire.DecodeZigZag(f.val)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	d.node(id, b.coordinate(lat, lon), b.tags(keys, vals))
+	return nil
+}
+
+func (b *pbfBlock) denseNodes(msg []byte, d *osmData) error {
+	var ids, lats, lons []int64
+	var keysVals []uint64
+	err := pbfFields(msg, func(f pbfField) error {
+		var err error
+		switch f.num {
+		case 1:
+			ids, err = f.deltas()
+		case 8:
+			lats, err = f.deltas()
+		case 9:
+			lons, err = f.deltas()
+		case 10:
+			keysVals, err = f.varints()
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return fmt.Errorf("dense nodes have %d ids, %d latitudes and %d longitudes", len(ids), len(lats), len(lons))
+	}
+	// keysVals lists key, value string indices for each node, ending with 0
+	for i, id := range ids {
+		var keys, vals []uint64
+		for len(keysVals) > 0 && keysVals[0] != 0 && len(keysVals) > 1 {
+			keys = append(keys, keysVals[0])
+			vals = append(vals, keysVals[1])
+			keysVals = keysVals[2:]
+		}
+		if len(keysVals) > 0 {
+			keysVals = keysVals[1:]
+		}
+		d.node(id, b.coordinate(lats[i], lons[i]), b.tags(keys, vals))
+	}
+	return nil
+}
+
+func (b *pbfBlock) way(msg []byte, d *osmData) error {
+	var keys, vals []uint64
+	var refs []int64
+	err := pbfFields(msg, func(f pbfField) error {
+		var err error
+		switch f.num {
+		case 2:
+			keys, err = f.varints()
+		case 3:
+			vals, err = f.varints()
+		case 8:
+			refs, err = f.deltas()
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	d.way(refs, b.tags(keys, vals))
+	return nil
+}
@@ -0,0 +1,145 @@
+package graphs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// pbfBlob frames a block as a BlobHeader and a zlib-compressed Blob.
+func pbfBlob(t *testing.T, kind string, block []byte) []byte {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	_, err := zw.Write(block)
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	var blob []byte
+	blob = protowire.AppendTag(blob, 2, protowire.VarintType)
+	blob = protowire.AppendVarint(blob, uint64(len(block)))
+	blob = protowire.AppendTag(blob, 3, protowire.BytesType)
+	blob = protowire.AppendBytes(blob, z.Bytes())
+
+	var header []byte
+	header = protowire.AppendTag(header, 1, protowire.BytesType)
+	header = protowire.AppendString(header, kind)
+	header = protowire.AppendTag(header, 3, protowire.VarintType)
+	header = protowire.AppendVarint(header, uint64(len(blob)))
+
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(header)))
+	return append(append(out, header...), blob...)
+}
+
+func pbfPacked(field protowire.Number, values []uint64) []byte {
+	var packed []byte
+	for _, v := range values {
+		packed = protowire.AppendVarint(packed, v)
+	}
+	b := protowire.AppendTag(nil, field, protowire.BytesType)
+	return protowire.AppendBytes(b, packed)
+}
+
+func pbfDelta(values []int64) []uint64 {
+	out := make([]uint64, len(values))
+	var prev int64
+	for i, v := range values {
+		out[i] = protowire.EncodeZigZag(v - prev)
+		prev = v
+	}
+	return out
+}
+
+// osmPBF encodes the nodes and ways of osmExtract, with the nodes dense.
+func osmPBF(t *testing.T, features ...string) []byte {
+	strs := []string{"", "railway", "station", "name", "Hamburg Hbf", "Bergedorf", "Tiefstack", "Far Away", "rail", "highway", "primary"}
+	type node struct {
+		id       int64
+		lat, lon float64
+		tags     []uint64
+	}
+	nodes := []node{
+		{1, 53.55, 10.00, []uint64{1, 2, 3, 4}}, {2, 53.55, 10.05, nil}, {3, 53.55, 10.10, nil}, {4, 53.55, 10.20, nil},
+		{5, 53.55, 10.30, []uint64{1, 2, 3, 5}}, {10, 53.553, 10.10, []uint64{1, 2, 3, 6}},
+		{11, 53.70, 10.10, []uint64{1, 2, 3, 7}}, {12, 53.555, 10.00, []uint64{1, 2}},
+	}
+	var ids, lats, lons []int64
+	var keysVals []uint64
+	for _, n := range nodes {
+		// granularity 100 nanodegrees
+		ids = append(ids, n.id)
+		lats = append(lats, int64(n.lat*1e7+0.5))
+		lons = append(lons, int64(n.lon*1e7+0.5))
+		keysVals = append(append(keysVals, n.tags...), 0)
+	}
+	var dense []byte
+	dense = append(dense, pbfPacked(1, pbfDelta(ids))...)
+	dense = append(dense, pbfPacked(8, pbfDelta(lats))...)
+	dense = append(dense, pbfPacked(9, pbfDelta(lons))...)
+	dense = append(dense, pbfPacked(10, keysVals)...)
+
+	var group []byte
+	group = protowire.AppendTag(group, 2, protowire.BytesType)
+	group = protowire.AppendBytes(group, dense)
+	for _, w := range []struct {
+		refs       []int64
+		keys, vals []uint64
+	}{
+		{[]int64{1, 2, 3}, []uint64{1}, []uint64{8}},
+		{[]int64{3, 4, 5, 99}, []uint64{1}, []uint64{8}},
+		{[]int64{1, 5}, []uint64{9}, []uint64{10}},
+	} {
+		var way []byte
+		way = protowire.AppendTag(way, 1, protowire.VarintType)
+		way = protowire.AppendVarint(way, 100)
+		way = append(way, pbfPacked(2, w.keys)...)
+		way = append(way, pbfPacked(3, w.vals)...)
+		way = append(way, pbfPacked(8, pbfDelta(w.refs))...)
+		group = protowire.AppendTag(group, 3, protowire.BytesType)
+		group = protowire.AppendBytes(group, way)
+	}
+
+	var table []byte
+	for _, s := range strs {
+		table = protowire.AppendTag(table, 1, protowire.BytesType)
+		table = protowire.AppendString(table, s)
+	}
+	var block []byte
+	block = protowire.AppendTag(block, 1, protowire.BytesType)
+	block = protowire.AppendBytes(block, table)
+	block = protowire.AppendTag(block, 2, protowire.BytesType)
+	block = protowire.AppendBytes(block, group)
+
+	var header []byte
+	for _, f := range append([]string{"OsmSchema-V0.6", "DenseNodes"}, features...) {
+		header = protowire.AppendTag(header, 4, protowire.BytesType)
+		header = protowire.AppendString(header, f)
+	}
+	return append(pbfBlob(t, "OSMHeader", header), pbfBlob(t, "OSMData", block)...)
+}
+
+func TestImportOSMPBF(t *testing.T) {
+	fromXML := NewGraph()
+	xmlWarnings, err := fromXML.ImportWithWarnings(strings.NewReader(osmExtract), FormatOSM)
+	assert.NoError(t, err)
+
+	g := NewGraph()
+	warnings, err := g.ImportWithWarnings(bytes.NewReader(osmPBF(t)), FormatOSMPBF)
+	assert.NoError(t, err)
+	assert.Equal(t, fromXML.Nodes(), g.Nodes())
+	assert.Equal(t, xmlWarnings, warnings)
+}
+
+func TestImportOSMPBFErrors(t *testing.T) {
+	g := NewGraph()
+	err := g.Import(bytes.NewReader(osmPBF(t, "HistoricalInformation")), FormatOSMPBF)
+	assert.EqualError(t, err, `invalid osm pbf: required feature "HistoricalInformation" is not supported`)
+
+	data := osmPBF(t)
+	err = g.Import(bytes.NewReader(data[:len(data)-10]), FormatOSMPBF)
+	assert.EqualError(t, err, "invalid osm pbf: unexpected EOF")
+}
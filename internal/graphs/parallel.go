@@ -0,0 +1,119 @@
+package graphs
+
+import (
+	"context"
+	"sync"
+)
+
+// cancelCheckInterval is how many DFS steps run between context checks.
+const cancelCheckInterval = 1024
+
+// EnumOptions controls how route enumeration is executed.
+type EnumOptions struct {
+	// Workers is the number of goroutines the first-level branches of the
+	// search are distributed across. Zero or one runs sequentially.
+	Workers int
+	// Stats, if not nil, receives the work done by the enumeration, summed
+	// over all branches.
+	Stats *SearchStats
+	// Trips, if not nil, receives the first TripLimit trips counted, in the
+	// order they were found, branch by branch. Only the trip counters fill
+	// it in.
+	Trips *TripList
+	// TripLimit caps Trips; zero means DefaultTripLimit.
+	TripLimit int
+}
+
+// forEachBranch calls explore once for every branch index in [0, n) using
+// at most workers goroutines. Branches write their own results, so callers
+// merge them in index order and get the same answer for any worker count.
+// The first error, including cancellation of ctx, stops the remaining
+// branches and is returned.
+func forEachBranch(ctx context.Context, n, workers int, explore func(ctx context.Context, branch int) error) error {
+	if workers <= 1 || n <= 1 {
+		for b := 0; b < n; b++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := explore(ctx, b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	branches := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for w := 0; w < min(workers, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range branches {
+				if err := explore(ctx, b); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+feed:
+	for b := 0; b < n; b++ {
+		select {
+		case branches <- b:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(branches)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// branchStats returns per-branch statistics for n branches, or nil when
+// opts does not collect them.
+func (opts EnumOptions) branchStats(n int) []SearchStats {
+	if opts.Stats == nil {
+		return nil
+	}
+	return make([]SearchStats, n)
+}
+
+// statsFor returns the statistics of branch b, or nil.
+func statsFor(stats []SearchStats, b int) *SearchStats {
+	if stats == nil {
+		return nil
+	}
+	return &stats[b]
+}
+
+// merge adds the branch statistics to opts.Stats in branch order.
+func (opts EnumOptions) merge(stats []SearchStats) {
+	for _, s := range stats {
+		opts.Stats.add(s)
+	}
+}
+
+// canceller checks ctx every cancelCheckInterval steps of a search.
+type canceller struct {
+	ctx   context.Context
+	steps int
+}
+
+func (c *canceller) check() error {
+	c.steps++
+	if c.steps%cancelCheckInterval == 0 {
+		return c.ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package graphs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aashi1008/hamburg-rails/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelEnumerationMatchesSequential(t *testing.T) {
+	g := denseGraph(t)
+	ctx := context.Background()
+	req := models.RouteSearchRequest{Constraints: models.RouteSearchConstraints{MaxDistance: 30}, Limit: 25}
+
+	wantStops := g.CountTripsByStops("A", "Z", 1, 4)
+	wantDistance := g.CountTripsByDistance("A", "Z", 40)
+	wantRoutes := g.SearchRoutes("A", "Z", req)
+	assert.NotZero(t, wantStops)
+	assert.NotEmpty(t, wantRoutes.Routes)
+
+	for _, workers := range []int{2, 4, 16} {
+		opts := EnumOptions{Workers: workers}
+		stops, err := g.CountTripsByStopsContext(ctx, "A", "Z", 1, 4, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, wantStops, stops)
+
+		distance, err := g.CountTripsByDistanceContext(ctx, "A", "Z", 40, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, wantDistance, distance)
+
+		routes, err := g.SearchRoutesContext(ctx, "A", "Z", req, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, wantRoutes, routes)
+	}
+}
+
+func TestParallelEnumerationSeedGraph(t *testing.T) {
+	g := seedGraph()
+	opts := EnumOptions{Workers: 3}
+
+	count, err := g.CountTripsByStopsContext(context.Background(), "C", "C", 1, 3, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = g.CountTripsByDistanceContext(context.Background(), "C", "C", 30, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, count)
+
+	count, err = g.CountTripsByStopsContext(context.Background(), "A", "A", 0, 0, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestParallelEnumerationCancelled(t *testing.T) {
+	g := denseGraph(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, workers := range []int{1, 4} {
+		opts := EnumOptions{Workers: workers}
+		_, err := g.CountTripsByStopsContext(ctx, "A", "Z", 1, 8, opts)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = g.CountTripsByDistanceContext(ctx, "A", "Z", 200, opts)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = g.SearchRoutesContext(ctx, "A", "Z", models.RouteSearchRequest{Constraints: models.RouteSearchConstraints{MaxStops: 8}}, opts)
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
+
+func TestForEachBranchStopsOnError(t *testing.T) {
+	boom := errors.New("boom")
+	err := forEachBranch(context.Background(), 100, 4, func(ctx context.Context, b int) error {
+		if b == 3 {
+			return boom
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, boom)
+}
@@ -0,0 +1,250 @@
+package graphs
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+)
+
+// SampleMode selects which counter's constraints a sample follows.
+type SampleMode string
+
+const (
+	// SampleByStops samples the trips counted by CountTripsByStops.
+	SampleByStops SampleMode = "stops"
+	// SampleByDistance samples the trips counted by CountTripsByDistance.
+	SampleByDistance SampleMode = "distance"
+)
+
+// ParseSampleMode validates a sample mode, defaulting to stops when empty.
+func ParseSampleMode(s string) (SampleMode, error) {
+	switch SampleMode(s) {
+	case "", SampleByStops:
+		return SampleByStops, nil
+	case SampleByDistance:
+		return SampleByDistance, nil
+	}
+	return "", fmt.Errorf("invalid sample mode: %q", s)
+}
+
+const (
+	// MaxSampleCount bounds the trips drawn by one SampleTrips call.
+	MaxSampleCount = 1000
+	// maxSampleCells bounds the dynamic programming table, whose size is
+	// the number of towns times maxStops or, in distance mode, the number
+	// of distinct trip lengths below maxDistance.
+	maxSampleCells = 1 << 20
+)
+
+// SampleOptions are the constraints and size of a sample.
+type SampleOptions struct {
+	Mode        SampleMode
+	MinStops    int
+	MaxStops    int
+	MaxDistance int
+	// Count is the number of trips to draw.
+	Count int
+	Seed  int64
+	// Stats, if not nil, receives the work done: every table cell filled
+	// counts as an expanded node.
+	Stats *SearchStats
+}
+
+// Sample is a set of trips drawn uniformly, with replacement, from all the
+// trips satisfying the constraints. Total is their number, which can be far
+// too large for an int.
+type Sample struct {
+	Total *big.Int `json:"total"`
+	Seed  int64    `json:"seed"`
+	Trips []Trip   `json:"trips"`
+}
+
+// SampleTrips draws opts.Count trips from one town to another uniformly at
+// random among all trips satisfying the constraints of the counter selected
+// by opts.Mode, without enumerating them: the number of completions from
+// every town is computed once by dynamic programming and each trip is drawn
+// by descending through those counts with a single random number. The same
+// seed gives the same trips for the same graph. It returns ErrNoSuchRoute
+// when no trip satisfies the constraints.
+func (g *Graph) SampleTrips(from, to string, opts SampleOptions) (Sample, error) {
+	defer opts.Stats.timed(time.Now())
+	if opts.Count < 1 || opts.Count > MaxSampleCount {
+		return Sample{}, fmt.Errorf("count must be between 1 and %d", MaxSampleCount)
+	}
+	c := g.snapshot()
+	src, okFrom := c.id(from)
+	dst, okTo := c.id(to)
+	if !okFrom || !okTo {
+		return Sample{}, ErrNoSuchRoute
+	}
+
+	// ways counts the walks from every town to dst of exactly k stops
+	// (stops mode) or exactly distance k (distance mode); lengths lists the
+	// trip lengths allowed from src.
+	var ways walkTable
+	var lengths []int
+	var cost func(slot int) int
+	switch opts.Mode {
+	case SampleByStops:
+		if opts.MinStops < 0 || opts.MinStops > opts.MaxStops {
+			return Sample{}, fmt.Errorf("invalid stop range %d..%d", opts.MinStops, opts.MaxStops)
+		}
+		// divide rather than multiply so that huge limits cannot overflow
+		if opts.MaxStops >= maxSampleCells/c.size() {
+			return Sample{}, fmt.Errorf("maxStops too large to sample")
+		}
+		cost = func(int) int { return 1 }
+		sums := make([]int, opts.MaxStops+1)
+		for k := range sums {
+			sums[k] = k
+		}
+		ways = walkCounts(c, dst, sums, cost, opts.Stats)
+		lengths = sums[opts.MinStops:]
+	case SampleByDistance:
+		if opts.MaxDistance <= 0 {
+			return Sample{}, fmt.Errorf("maxDistance must be > 0")
+		}
+		// only the lengths a walk can have get a row, so the table does
+		// not grow with the distance scale
+		sums, ok := walkSums(c, opts.MaxDistance, maxSampleCells/c.size())
+		if !ok {
+			return Sample{}, fmt.Errorf("maxDistance too large to sample")
+		}
+		cost = func(i int) int { return c.dists[i] }
+		ways = walkCounts(c, dst, sums, cost, opts.Stats)
+		lengths = sums[1:]
+	default:
+		return Sample{}, fmt.Errorf("invalid sample mode: %q", opts.Mode)
+	}
+	total := new(big.Int)
+	for _, k := range lengths {
+		total.Add(total, ways.at(k, src))
+	}
+	if total.Sign() == 0 {
+		return Sample{}, ErrNoSuchRoute
+	}
+
+	rnd := rand.New(rand.NewSource(opts.Seed))
+	res := Sample{Total: total, Seed: opts.Seed, Trips: make([]Trip, 0, opts.Count)}
+	x := new(big.Int)
+	for n := 0; n < opts.Count; n++ {
+		// x picks one trip by its rank; every choice below narrows it to the
+		// ones sharing that choice
+		x.Rand(rnd, total)
+		length := lengths[len(lengths)-1]
+		for _, k := range lengths {
+			if x.Cmp(ways.at(k, src)) < 0 {
+				length = k
+				break
+			}
+			x.Sub(x, ways.at(k, src))
+		}
+		ids := []int{src}
+		distance := 0
+		for v, left := src, length; left > 0; {
+			lo, hi := c.out(v)
+			for i := lo; i < hi; i++ {
+				opts.Stats.relax()
+				step := cost(i)
+				if step > left {
+					continue
+				}
+				w := ways.at(left-step, int(c.targets[i]))
+				if x.Cmp(w) < 0 {
+					v, left = int(c.targets[i]), left-step
+					distance += c.dists[i]
+					ids = append(ids, v)
+					break
+				}
+				x.Sub(x, w)
+			}
+		}
+		res.Trips = append(res.Trips, Trip{Path: c.towns(ids), Distance: distance, Stops: len(ids) - 1})
+	}
+	return res, nil
+}
+
+// walkTable holds the number of walks to one town from every town, for
+// each of a set of walk lengths.
+type walkTable struct {
+	row  map[int]int
+	ways [][]big.Int
+}
+
+// noWalks is the count of lengths outside the table; never modified.
+var noWalks big.Int
+
+// at returns the number of walks from v of length k.
+func (t walkTable) at(k, v int) *big.Int {
+	r, ok := t.row[k]
+	if !ok {
+		return &noWalks
+	}
+	return &t.ways[r][v]
+}
+
+// walkCounts counts the walks from every town to dst whose edges add up to
+// exactly each of sums under cost. sums must be sorted, start at 0 and hold
+// every sum of costs a walk can have up to its last. Costs must be
+// positive.
+func walkCounts(c *csr, dst int, sums []int, cost func(slot int) int, stats *SearchStats) walkTable {
+	t := walkTable{row: make(map[int]int, len(sums)), ways: make([][]big.Int, len(sums))}
+	for r, k := range sums {
+		t.row[k] = r
+		t.ways[r] = make([]big.Int, c.size())
+	}
+	t.ways[0][dst].SetInt64(1)
+	for r := 1; r < len(sums); r++ {
+		k := sums[r]
+		for v := 0; v < c.size(); v++ {
+			stats.expand()
+			lo, hi := c.out(v)
+			for i := lo; i < hi; i++ {
+				stats.relax()
+				if step := cost(i); step <= k {
+					t.ways[r][v].Add(&t.ways[r][v], t.at(k-step, int(c.targets[i])))
+				}
+			}
+		}
+	}
+	return t
+}
+
+// walkSums returns 0 and, in order, every sum of edge distances below limit,
+// which covers every length a walk can have. It reports false when there
+// are more than most of them.
+func walkSums(c *csr, limit, most int) ([]int, bool) {
+	var costs []int
+	seen := map[int]bool{}
+	for _, d := range c.dists {
+		if !seen[d] {
+			seen[d] = true
+			costs = append(costs, d)
+		}
+	}
+	// merge the sequences sums[next[j]] + costs[j], each already sorted
+	sums := []int{0}
+	next := make([]int, len(costs))
+	for {
+		best := -1
+		for j, d := range costs {
+			// compared by difference so that huge limits cannot overflow
+			if d < limit-sums[next[j]] && (best < 0 || sums[next[j]]+d < best) {
+				best = sums[next[j]] + d
+			}
+		}
+		if best < 0 {
+			return sums, true
+		}
+		if len(sums) >= most {
+			return nil, false
+		}
+		sums = append(sums, best)
+		for j, d := range costs {
+			if sums[next[j]]+d == best {
+				next[j]++
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package graphs
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleTripsTotalsMatchCounters(t *testing.T) {
+	g := seedGraph()
+
+	s, err := g.SampleTrips("C", "C", SampleOptions{Mode: SampleByStops, MinStops: 1, MaxStops: 3, Count: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(g.CountTripsByStops("C", "C", 1, 3)), s.Total.Int64())
+
+	s, err = g.SampleTrips("A", "C", SampleOptions{Mode: SampleByStops, MinStops: 4, MaxStops: 4, Count: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(g.CountTripsByStops("A", "C", 4, 4)), s.Total.Int64())
+	for _, trip := range s.Trips {
+		assert.Equal(t, 4, trip.Stops)
+	}
+
+	s, err = g.SampleTrips("C", "C", SampleOptions{Mode: SampleByDistance, MaxDistance: 30, Count: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), s.Total.Int64())
+	for _, trip := range s.Trips {
+		d, err := g.Distance(trip.Path)
+		assert.NoError(t, err)
+		assert.Equal(t, d, trip.Distance)
+		assert.Less(t, trip.Distance, 30)
+		assert.Equal(t, "C", trip.Path[0])
+		assert.Equal(t, "C", trip.Path[len(trip.Path)-1])
+	}
+}
+
+func TestSampleTripsDecimalDistances(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB100.001", "BC100.002", "CD100.003", "DA100.004"}))
+	// 300.5 km is 300500 steps, which would not fit one row per step
+	limit := 300500
+	s, err := g.SampleTrips("A", "C", SampleOptions{Mode: SampleByDistance, MaxDistance: limit, Count: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(g.CountTripsByDistance("A", "C", limit)), s.Total.Int64())
+	assert.Equal(t, []Trip{
+		{Path: []string{"A", "B", "C"}, Distance: 200003, Stops: 2},
+		{Path: []string{"A", "B", "C"}, Distance: 200003, Stops: 2},
+		{Path: []string{"A", "B", "C"}, Distance: 200003, Stops: 2},
+	}, s.Trips)
+}
+
+func TestSampleTripsUniform(t *testing.T) {
+	g := seedGraph()
+	s, err := g.SampleTrips("C", "C", SampleOptions{Mode: SampleByDistance, MaxDistance: 30, Count: MaxSampleCount, Seed: 7})
+	assert.NoError(t, err)
+	seen := map[string]int{}
+	for _, trip := range s.Trips {
+		seen[strings.Join(trip.Path, "")]++
+	}
+	// all seven trips show up, each near 1000/7
+	assert.Len(t, seen, 7)
+	for path, n := range seen {
+		assert.InDelta(t, MaxSampleCount/7, n, 60, path)
+	}
+}
+
+func TestSampleTripsSeeded(t *testing.T) {
+	g := denseGraph(t)
+	opts := SampleOptions{Mode: SampleByStops, MinStops: 1, MaxStops: 5, Count: 10, Seed: 42}
+	a, err := g.SampleTrips("A", "Z", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(g.CountTripsByStops("A", "Z", 1, 5)), a.Total.Int64())
+	b, err := g.SampleTrips("A", "Z", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	// far more trips than fit in an int64, let alone enumerate
+	long, err := g.SampleTrips("A", "Z", SampleOptions{Mode: SampleByStops, MinStops: 40, MaxStops: 40, Count: 3, Seed: 42})
+	assert.NoError(t, err)
+	assert.Greater(t, long.Total.BitLen(), 63)
+	for _, trip := range long.Trips {
+		assert.Equal(t, 40, trip.Stops)
+		_, err := g.Distance(trip.Path)
+		assert.NoError(t, err)
+	}
+
+	opts.Seed = 43
+	c, err := g.SampleTrips("A", "Z", opts)
+	assert.NoError(t, err)
+	assert.NotEqual(t, a.Trips, c.Trips)
+}
+
+func TestSampleTripsErrors(t *testing.T) {
+	g := seedGraph()
+	_, err := g.SampleTrips("C", "A", SampleOptions{Mode: SampleByStops, MinStops: 1, MaxStops: 5, Count: 1})
+	assert.True(t, errors.Is(err, ErrNoSuchRoute))
+
+	_, err = g.SampleTrips("A", "C", SampleOptions{Mode: SampleByStops, MinStops: 1, MaxStops: 5, Count: 0})
+	assert.Error(t, err)
+
+	_, err = g.SampleTrips("A", "C", SampleOptions{Mode: SampleByDistance, MaxDistance: 1 << 30, Count: 1})
+	assert.Error(t, err)
+
+	// limits whose table size overflows an int are still refused
+	small := NewGraph()
+	assert.NoError(t, small.LoadEdges([]string{"AB1", "BC1", "CD1", "DA1"}))
+	_, err = small.SampleTrips("A", "C", SampleOptions{Mode: SampleByStops, MinStops: 1, MaxStops: 4611686018427387903, Count: 1})
+	assert.EqualError(t, err, "maxStops too large to sample")
+	_, err = small.SampleTrips("A", "C", SampleOptions{Mode: SampleByDistance, MaxDistance: math.MaxInt, Count: 1})
+	assert.EqualError(t, err, "maxDistance too large to sample")
+
+	stats := &SearchStats{}
+	_, err = g.SampleTrips("A", "C", SampleOptions{Mode: SampleByStops, MinStops: 1, MaxStops: 3, Count: 1, Stats: stats})
+	assert.NoError(t, err)
+	// three layers of five towns
+	assert.Equal(t, 15, stats.NodesExpanded)
+
+	_, err = ParseSampleMode("walk")
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package graphs
+
+import "sort"
+
+// AsymmetricPair is a pair of towns not joined the same way in both
+// directions: To->From is missing, or has a different distance.
+// Distances are in units of the graph.
+type AsymmetricPair struct {
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Distance float64 `json:"distance"`
+	// ReverseDistance is the distance of To->From, zero when it is missing.
+	ReverseDistance float64 `json:"reverseDistance,omitempty"`
+}
+
+// mirrorEdges adds the reverse of every edge whose reverse is missing.
+func mirrorEdges(edges []rawEdge) []rawEdge {
+	seen := make(map[[2]string]bool, len(edges))
+	for _, e := range edges {
+		seen[[2]string{e.from, e.to}] = true
+	}
+	mirrored := edges
+	for _, e := range edges {
+		if !seen[[2]string{e.to, e.from}] {
+			mirrored = append(mirrored, rawEdge{from: e.to, to: e.from, distance: e.distance, capacity: e.capacity})
+		}
+	}
+	return mirrored
+}
+
+// asymmetricPairs returns the first maxImportWarnings asymmetric pairs in
+// order of towns, along with how many there are. Pairs with two distances
+// are given once, from the town first in alphabetical order. Distances are
+// in steps of 1/scale.
+func asymmetricPairs(edges []rawEdge, scale int) ([]AsymmetricPair, int) {
+	unit := func(d int) float64 { return float64(d) / float64(scale) }
+	dist := make(map[[2]string]int, len(edges))
+	for _, e := range edges {
+		dist[[2]string{e.from, e.to}] = e.distance
+	}
+	var pairs []AsymmetricPair
+	for _, e := range edges {
+		reverse, ok := dist[[2]string{e.to, e.from}]
+		switch {
+		case !ok:
+			pairs = append(pairs, AsymmetricPair{From: e.from, To: e.to, Distance: unit(e.distance)})
+		case reverse != e.distance && e.from < e.to:
+			pairs = append(pairs, AsymmetricPair{From: e.from, To: e.to, Distance: unit(e.distance), ReverseDistance: unit(reverse)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].From != pairs[j].From {
+			return pairs[i].From < pairs[j].From
+		}
+		return pairs[i].To < pairs[j].To
+	})
+	count := len(pairs)
+	if count > maxImportWarnings {
+		pairs = pairs[:maxImportWarnings]
+	}
+	return pairs, count
+}
@@ -0,0 +1,74 @@
+package graphs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadEdgesArrows(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"A<->B:5", "b -> c : 4/2", "ALTONA<->BC:3"}))
+	assert.Equal(t, []EdgeSpec{
+		{From: "A", To: "B", Distance: 5},
+		{From: "ALTONA", To: "BC", Distance: 3},
+		{From: "B", To: "A", Distance: 5},
+		{From: "B", To: "C", Distance: 4, Capacity: 2},
+		{From: "BC", To: "ALTONA", Distance: 3},
+	}, g.EdgeList())
+
+	err := g.LoadEdges([]string{"A<->B:5", "BA5", "A<>B:1", "A<->A:2"})
+	var errs ParseErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, 3, len(errs))
+	assert.True(t, errors.Is(errs[0], ErrInvalidToken))
+	assert.Equal(t, `"BA5": duplicate edge B->A`, errs[1].Error())
+	assert.True(t, errors.Is(errs[2], ErrSelfLoop))
+}
+
+func TestImportBidirectional(t *testing.T) {
+	g := NewGraph()
+	input := `[{"from":"A","to":"B","distance":5,"bidirectional":true},{"from":"B","to":"A","distance":5}]`
+	err := g.Import(strings.NewReader(input), FormatJSON)
+	assert.EqualError(t, err, `line 1, column 58: "{\"from\":\"B\",\"to\":\"A\",\"distance\":5}": duplicate edge B->A, first at line 1, column 2`)
+
+	report, err := g.ImportWithOptions(strings.NewReader("A<->B:5\nBC4"), FormatText, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []AsymmetricPair{{From: "B", To: "C", Distance: 4}}, report.Asymmetric)
+}
+
+func TestImportMirror(t *testing.T) {
+	g := NewGraph()
+	report, err := g.ImportWithOptions(strings.NewReader("AB5, BA7, BC4/2"), FormatText, ImportOptions{Mirror: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []EdgeSpec{
+		{From: "A", To: "B", Distance: 5},
+		{From: "B", To: "A", Distance: 7},
+		{From: "B", To: "C", Distance: 4, Capacity: 2},
+		{From: "C", To: "B", Distance: 4, Capacity: 2},
+	}, g.EdgeList())
+	// an explicit reverse is kept, so the pair stays asymmetric
+	assert.Equal(t, []AsymmetricPair{{From: "A", To: "B", Distance: 5, ReverseDistance: 7}}, report.Asymmetric)
+	assert.Equal(t, 1, report.AsymmetricCount)
+}
+
+func TestAsymmetricPairs(t *testing.T) {
+	g := NewGraph()
+	report, err := g.ImportWithOptions(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"), FormatText, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, report.AsymmetricCount)
+	assert.Equal(t, AsymmetricPair{From: "A", To: "B", Distance: 5}, report.Asymmetric[0])
+	for _, p := range report.Asymmetric {
+		assert.False(t, p.From == "C" && p.To == "D" || p.From == "D" && p.To == "C")
+	}
+
+	var edges []rawEdge
+	for i := 0; i < maxImportWarnings+5; i++ {
+		edges = append(edges, rawEdge{from: "A", to: strings.Repeat("B", i%16+1) + strings.Repeat("C", i/16+1), distance: 1})
+	}
+	pairs, count := asymmetricPairs(edges, 1)
+	assert.Equal(t, maxImportWarnings, len(pairs))
+	assert.Equal(t, maxImportWarnings+5, count)
+}
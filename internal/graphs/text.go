@@ -0,0 +1,173 @@
+package graphs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxIncludeDepth bounds how deeply text graph files may include others.
+const maxIncludeDepth = 8
+
+// textImporter reads the original format: `AB5` tokens separated by commas
+// and newlines, ignoring punctuation around each token. A `#` starts a
+// comment running to the end of the line, and lines starting with `@` are
+// directives:
+//
+//	@name Hamburg S-Bahn
+//	@version 2024.1
+//	@units km
+//	@include lines/s1.txt
+//
+// @name, @version and @units make up the Metadata and must come before the
+// first edge, in the input itself rather than an included file; @units names
+// one of Units or their aliases.
+// @include reads the edges of another file, relative to the including one,
+// as if they were part of the input.
+type textImporter struct{}
+
+func (textImporter) Import(r io.Reader) ([]EdgeSpec, error) {
+	res, err := textImporter{}.ImportResult(r, ImportOptions{})
+	return res.Edges, err
+}
+
+func (textImporter) ImportResult(r io.Reader, opts ImportOptions) (ImportResult, error) {
+	t := &textReader{includes: opts.Includes}
+	if err := t.read(r, opts.Name); err != nil {
+		return ImportResult{}, err
+	}
+	specs, errs := parseTokens(t.tokens)
+	return ImportResult{Edges: specs, Metadata: t.meta}, append(t.errs, errs...).err()
+}
+
+// textReader collects the tokens of a text graph and the files it includes.
+type textReader struct {
+	includes fs.FS
+	// open lists the files being read, the input first.
+	open   []string
+	tokens []Source
+	// edges is set once the first edge is read, in any file, ending the
+	// header.
+	edges bool
+	meta  Metadata
+	errs  ParseErrors
+}
+
+func (t *textReader) read(r io.Reader, name string) error {
+	// positions in the input itself are given without a file name
+	file := name
+	if len(t.open) == 0 {
+		file = ""
+	}
+	t.open = append(t.open, name)
+	defer func() { t.open = t.open[:len(t.open)-1] }()
+
+	scan := bufio.NewScanner(r)
+	for line := 1; scan.Scan(); line++ {
+		text := scan.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "@") {
+			column := utf8.RuneCountInString(text[:strings.IndexByte(text, '@')]) + 1
+			src := Source{File: file, Line: line, Column: column, Text: trimmed}
+			if err := t.directive(src); err != nil {
+				return err
+			}
+			continue
+		}
+		column := 1
+		for _, field := range strings.Split(text, ",") {
+			trimmed := strings.TrimFunc(field, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+			})
+			if trimmed != "" {
+				lead := strings.Index(field, trimmed)
+				t.tokens = append(t.tokens, Source{File: file, Line: line, Column: column + utf8.RuneCountInString(field[:lead]), Text: trimmed})
+				t.edges = true
+			}
+			column += utf8.RuneCountInString(field) + 1
+		}
+	}
+	return scan.Err()
+}
+
+// directive applies the directive at src. Problems with it are recorded
+// as ParseErrors; only failing to read an included file is returned.
+func (t *textReader) directive(src Source) error {
+	name, arg := src.Text, ""
+	if i := strings.IndexFunc(src.Text, unicode.IsSpace); i >= 0 {
+		name, arg = src.Text[:i], strings.TrimSpace(src.Text[i:])
+	}
+	var field *string
+	switch name {
+	case "@name":
+		field = &t.meta.Name
+	case "@version":
+		field = &t.meta.Version
+	case "@units":
+		field = &t.meta.Units
+	case "@include":
+		return t.include(src, arg)
+	default:
+		t.errs.add(src, fmt.Errorf("%w: unknown directive %s", ErrInvalidDirective, name))
+		return nil
+	}
+	switch {
+	case arg == "":
+		t.errs.add(src, fmt.Errorf("%w: %s needs a value", ErrInvalidDirective, name))
+	case len(t.open) > 1:
+		t.errs.add(src, fmt.Errorf("%w: %s is not allowed in an included file", ErrInvalidDirective, name))
+	case t.edges:
+		t.errs.add(src, fmt.Errorf("%w: %s must come before the first edge", ErrInvalidDirective, name))
+	case *field != "":
+		t.errs.add(src, fmt.Errorf("%w: %s given twice", ErrInvalidDirective, name))
+	case name == "@units":
+		unit, err := ParseUnit(arg)
+		if err != nil {
+			t.errs.add(src, fmt.Errorf("%w: %v", ErrInvalidDirective, err))
+			return nil
+		}
+		*field = unit
+	default:
+		*field = arg
+	}
+	return nil
+}
+
+func (t *textReader) include(src Source, arg string) error {
+	name := path.Join(path.Dir(t.open[len(t.open)-1]), arg)
+	switch {
+	case arg == "":
+		t.errs.add(src, fmt.Errorf("%w: @include needs a file", ErrInvalidDirective))
+		return nil
+	case t.includes == nil:
+		t.errs.add(src, fmt.Errorf("%w: @include is only allowed in graph files", ErrInvalidDirective))
+		return nil
+	case !fs.ValidPath(name):
+		t.errs.add(src, fmt.Errorf("%w: %s is outside the graph directory", ErrInvalidDirective, arg))
+		return nil
+	case slices.Contains(t.open, name):
+		t.errs.add(src, fmt.Errorf("%w: %s includes itself", ErrInvalidDirective, name))
+		return nil
+	case len(t.open) > maxIncludeDepth:
+		t.errs.add(src, fmt.Errorf("%w: includes nested more than %d deep", ErrInvalidDirective, maxIncludeDepth))
+		return nil
+	}
+	f, err := t.includes.Open(name)
+	if err != nil {
+		t.errs.add(src, fmt.Errorf("%w: cannot include %s: %v", ErrInvalidDirective, arg, err))
+		return nil
+	}
+	defer f.Close()
+	if err := t.read(f, name); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package graphs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportTextDirectives(t *testing.T) {
+	g := NewGraph()
+	input := "# Hamburg test network\n@name  Hamburg S-Bahn \n@version 2024.1\n@units km # kilometres\nAB5, BC4 # the north\nCD8\n"
+	assert.NoError(t, g.Import(strings.NewReader(input), FormatText))
+	assert.Equal(t, Metadata{Name: "Hamburg S-Bahn", Version: "2024.1", Units: "km"}, g.Metadata())
+	assert.Equal(t, 3, len(g.EdgeList()))
+
+	// a load without a header clears the metadata
+	assert.NoError(t, g.LoadEdges([]string{"AB5"}))
+	assert.Equal(t, Metadata{}, g.Metadata())
+}
+
+func TestImportTextDirectiveErrors(t *testing.T) {
+	g := NewGraph()
+	input := "@name A\n@name B\nAB5\n@units km\n  @colour red\n@version\n@include other.txt\n"
+	err := g.Import(strings.NewReader(input), FormatText)
+	var errs ParseErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.True(t, errors.Is(err, ErrInvalidDirective))
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	assert.Equal(t, []string{
+		`line 2, column 1: "@name B": invalid directive: @name given twice`,
+		`line 4, column 1: "@units km": invalid directive: @units must come before the first edge`,
+		`line 5, column 3: "@colour red": invalid directive: unknown directive @colour`,
+		`line 6, column 1: "@version": invalid directive: @version needs a value`,
+		`line 7, column 1: "@include other.txt": invalid directive: @include is only allowed in graph files`,
+	}, msgs)
+	assert.Equal(t, 0, g.NodeCount())
+}
+
+func TestImportTextInclude(t *testing.T) {
+	files := fstest.MapFS{
+		"main.txt":          {Data: []byte("@name Hamburg\n@include lines/s1.txt\nAB5\n")},
+		"lines/s1.txt":      {Data: []byte("# S1\nBC4\n@include s1-east.txt\n")},
+		"lines/s1-east.txt": {Data: []byte("CD8, DA7\n")},
+	}
+	g := NewGraph()
+	f, _ := files.Open("main.txt")
+	_, err := g.ImportWithOptions(f, FormatText, ImportOptions{Includes: files, Name: "main.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, Metadata{Name: "Hamburg"}, g.Metadata())
+	assert.Equal(t, []EdgeSpec{
+		{From: "A", To: "B", Distance: 5},
+		{From: "B", To: "C", Distance: 4},
+		{From: "C", To: "D", Distance: 8},
+		{From: "D", To: "A", Distance: 7},
+	}, g.EdgeList())
+}
+
+func TestImportTextIncludeErrors(t *testing.T) {
+	files := fstest.MapFS{
+		"main.txt": {Data: []byte("@include a.txt\n@include ../secret.txt\n@include missing.txt\n")},
+		"a.txt":    {Data: []byte("AB5\n@include b.txt\n")},
+		"b.txt":    {Data: []byte("AB6, A1\n@include main.txt\n")},
+	}
+	f, _ := files.Open("main.txt")
+	_, err := NewGraph().ImportWithOptions(f, FormatText, ImportOptions{Includes: files, Name: "main.txt"})
+	var errs ParseErrors
+	assert.True(t, errors.As(err, &errs))
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	assert.Equal(t, []string{
+		`line 2, column 1: "@include ../secret.txt": invalid directive: ../secret.txt is outside the graph directory`,
+		`line 3, column 1: "@include missing.txt": invalid directive: cannot include missing.txt: open missing.txt: file does not exist`,
+		`b.txt line 1, column 1: "AB6": duplicate edge A->B, first at a.txt line 1, column 1`,
+		`b.txt line 1, column 6: "A1": invalid edge token`,
+		`b.txt line 2, column 1: "@include main.txt": invalid directive: main.txt includes itself`,
+	}, msgs)
+}
+
+func TestImportTextIncludeHeader(t *testing.T) {
+	files := fstest.MapFS{
+		"main.txt":  {Data: []byte("@name Hamburg\n@include s1.txt\n@version 2\nCD8\n")},
+		"s1.txt":    {Data: []byte("@name S1\n@units km\nBC4\n")},
+		"empty.txt": {Data: []byte("# no edges\n")},
+	}
+	f, _ := files.Open("main.txt")
+	_, err := NewGraph().ImportWithOptions(f, FormatText, ImportOptions{Includes: files, Name: "main.txt"})
+	var errs ParseErrors
+	assert.True(t, errors.As(err, &errs))
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	// the edges of s1.txt end the header of main.txt
+	assert.Equal(t, []string{
+		`line 3, column 1: "@version 2": invalid directive: @version must come before the first edge`,
+		`s1.txt line 1, column 1: "@name S1": invalid directive: @name is not allowed in an included file`,
+		`s1.txt line 2, column 1: "@units km": invalid directive: @units is not allowed in an included file`,
+	}, msgs)
+
+	// an include without edges leaves the header open
+	files["main.txt"] = &fstest.MapFile{Data: []byte("@include empty.txt\n@version 2\nCD8\n")}
+	g := NewGraph()
+	f, _ = files.Open("main.txt")
+	_, err = g.ImportWithOptions(f, FormatText, ImportOptions{Includes: files, Name: "main.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, Metadata{Version: "2"}, g.Metadata())
+}
+
+func TestLoadGraphFromFileInclude(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "lines"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "graph.txt"), []byte("@version 3\n@include lines/north.txt\nAB5\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "lines", "north.txt"), []byte("BC4\n"), 0o644))
+	g := NewGraph()
+	assert.NoError(t, g.LoadGraphFromFile(filepath.Join(dir, "graph.txt")))
+	assert.Equal(t, Metadata{Version: "3"}, g.Metadata())
+	assert.Equal(t, 2, g.NodeCount())
+}
@@ -0,0 +1,282 @@
+package graphs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultExactTourLimit is the largest number of towns to visit for which
+	// PlanTour solves the order exactly unless told otherwise.
+	DefaultExactTourLimit = 12
+	// MaxExactTourLimit bounds the exact solver, whose memory grows as n*2^n.
+	MaxExactTourLimit = 16
+
+	TourHeldKarp        = "held-karp"
+	TourNearestNeighbor = "nearest-neighbour+2-opt"
+)
+
+// TourOptions controls PlanTour.
+type TourOptions struct {
+	// Return makes the tour end back at the start town.
+	Return bool
+	// ExactLimit is the largest number of towns solved exactly with
+	// Held-Karp; nil means DefaultExactTourLimit and zero always uses the
+	// heuristic.
+	ExactLimit *int
+	// Stats, if not nil, receives the work done computing the distances
+	// between the towns.
+	Stats *SearchStats
+}
+
+// Tour is an ordered visit of a set of towns.
+type Tour struct {
+	Algorithm string   `json:"algorithm"`
+	Order     []string `json:"order"`
+	Path      []string `json:"path"`
+	Distance  int      `json:"distance"`
+}
+
+// PlanTour finds an order in which to visit every town in visit, starting
+// at start (and returning to it when opts.Return is set), minimising the
+// total distance over the shortest-path metric. Small sets are solved
+// exactly with Held-Karp, larger ones with nearest neighbour followed by
+// 2-opt. It returns ErrNoSuchRoute when some town cannot be reached.
+func (g *Graph) PlanTour(start string, visit []string, opts TourOptions) (Tour, error) {
+	defer opts.Stats.timed(time.Now())
+	limit := DefaultExactTourLimit
+	if opts.ExactLimit != nil {
+		limit = *opts.ExactLimit
+	}
+	if limit < 0 || limit > MaxExactTourLimit {
+		return Tour{}, fmt.Errorf("exact limit must be between 0 and %d", MaxExactTourLimit)
+	}
+
+	// stops[0] is the start, the rest the distinct towns to visit
+	stops := []string{start}
+	seen := map[string]bool{start: true}
+	for _, t := range visit {
+		if !seen[t] {
+			seen[t] = true
+			stops = append(stops, t)
+		}
+	}
+
+	n := len(stops)
+	cost, legs := g.distanceMatrix(stops, opts.Stats)
+
+	tour := Tour{Algorithm: TourHeldKarp}
+	var order []int
+	if n-1 <= limit {
+		order = heldKarp(cost, opts.Return)
+	} else {
+		tour.Algorithm = TourNearestNeighbor
+		// 2-opt only ever improves a valid tour
+		if order = nearestNeighbour(cost); order != nil {
+			order = twoOpt(cost, order, opts.Return)
+		}
+	}
+	if order == nil || tourCost(cost, order, opts.Return) == math.MaxInt {
+		return Tour{}, ErrNoSuchRoute
+	}
+
+	if opts.Return {
+		order = append(order, 0)
+	}
+	tour.Path = []string{start}
+	for k := 1; k < len(order); k++ {
+		i, j := order[k-1], order[k]
+		tour.Distance += cost[i][j]
+		if i != j {
+			tour.Path = append(tour.Path, legs[i][j][1:]...)
+		}
+	}
+	for _, i := range order {
+		tour.Order = append(tour.Order, stops[i])
+	}
+	return tour, nil
+}
+
+// distanceMatrix returns the shortest distance and path between every
+// ordered pair of towns, with math.MaxInt for unreachable pairs. It uses the
+// contraction hierarchy when one is ready and one Dijkstra per source
+// otherwise.
+func (g *Graph) distanceMatrix(towns []string, stats *SearchStats) ([][]int, [][][]string) {
+	n := len(towns)
+	cost := make([][]int, n)
+	legs := make([][][]string, n)
+	g.mutex.RLock()
+	c, ch := g.data, g.ch
+	g.mutex.RUnlock()
+	if stats != nil {
+		stats.Algorithm = AlgorithmDijkstra
+		if ch != nil {
+			stats.Algorithm = AlgorithmCH
+		}
+	}
+	for i, s := range towns {
+		cost[i] = make([]int, n)
+		legs[i] = make([][]string, n)
+		if ch != nil {
+			for j, t := range towns {
+				if i == j {
+					legs[i][j] = []string{s}
+					continue
+				}
+				d, path := ch.shortestPath(s, t, stats)
+				if d == -1 {
+					d = math.MaxInt
+				}
+				cost[i][j], legs[i][j] = d, path
+			}
+			continue
+		}
+		src, ok := c.id(s)
+		if !ok {
+			for j := range towns {
+				cost[i][j] = math.MaxInt
+			}
+			cost[i][i], legs[i][i] = 0, []string{s}
+			continue
+		}
+		dist, parent := dijkstraFrom(c, src, stats)
+		for j, t := range towns {
+			dst, ok := c.id(t)
+			if !ok || dist[dst] < 0 {
+				cost[i][j] = math.MaxInt
+				continue
+			}
+			if i == j {
+				cost[i][j], legs[i][j] = 0, []string{s}
+				continue
+			}
+			cost[i][j], legs[i][j] = dist[dst], c.towns(pathTo(parent, src, dst))
+		}
+	}
+	return cost, legs
+}
+
+// addCost adds two distances, keeping math.MaxInt as "unreachable".
+func addCost(a, b int) int {
+	if a == math.MaxInt || b == math.MaxInt {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+// tourCost is the length of visiting order, which always starts at 0.
+func tourCost(cost [][]int, order []int, ret bool) int {
+	total := 0
+	for k := 1; k < len(order); k++ {
+		total = addCost(total, cost[order[k-1]][order[k]])
+	}
+	if ret {
+		total = addCost(total, cost[order[len(order)-1]][0])
+	}
+	return total
+}
+
+// heldKarp returns the optimal visiting order of every stop starting at 0.
+func heldKarp(cost [][]int, ret bool) []int {
+	n := len(cost) - 1
+	if n == 0 {
+		return []int{0}
+	}
+	full := 1<<n - 1
+	dp := make([][]int, 1<<n)
+	from := make([][]int, 1<<n)
+	for mask := range dp {
+		dp[mask] = make([]int, n)
+		from[mask] = make([]int, n)
+		for j := range dp[mask] {
+			dp[mask][j] = math.MaxInt
+			from[mask][j] = -1
+		}
+	}
+	for j := 0; j < n; j++ {
+		dp[1<<j][j] = cost[0][j+1]
+	}
+	for mask := 1; mask <= full; mask++ {
+		for j := 0; j < n; j++ {
+			if mask&(1<<j) == 0 || dp[mask][j] == math.MaxInt {
+				continue
+			}
+			for k := 0; k < n; k++ {
+				if mask&(1<<k) != 0 {
+					continue
+				}
+				next := mask | 1<<k
+				if c := addCost(dp[mask][j], cost[j+1][k+1]); c < dp[next][k] {
+					dp[next][k] = c
+					from[next][k] = j
+				}
+			}
+		}
+	}
+
+	best, last := math.MaxInt, -1
+	for j := 0; j < n; j++ {
+		c := dp[full][j]
+		if ret {
+			c = addCost(c, cost[j+1][0])
+		}
+		if c < best {
+			best, last = c, j
+		}
+	}
+	if last < 0 {
+		return nil
+	}
+	order := make([]int, n+1)
+	for mask, j, k := full, last, n; k > 0; k-- {
+		order[k] = j + 1
+		mask, j = mask&^(1<<j), from[mask][j]
+	}
+	return order
+}
+
+// nearestNeighbour greedily builds an order starting at 0, always moving to
+// the closest unvisited stop reachable from the current one. It returns nil
+// when no unvisited stop is reachable.
+func nearestNeighbour(cost [][]int) []int {
+	n := len(cost)
+	order := []int{0}
+	used := make([]bool, n)
+	used[0] = true
+	for len(order) < n {
+		cur, next := order[len(order)-1], -1
+		for j := 1; j < n; j++ {
+			if !used[j] && cost[cur][j] != math.MaxInt && (next < 0 || cost[cur][j] < cost[cur][next]) {
+				next = j
+			}
+		}
+		if next < 0 {
+			return nil
+		}
+		used[next] = true
+		order = append(order, next)
+	}
+	return order
+}
+
+// twoOpt improves order by reversing segments while that shortens the tour.
+// Distances may be asymmetric, so every candidate is re-costed in full.
+func twoOpt(cost [][]int, order []int, ret bool) []int {
+	best := tourCost(cost, order, ret)
+	for improved := true; improved; {
+		improved = false
+		for i := 1; i < len(order)-1; i++ {
+			for j := i + 1; j < len(order); j++ {
+				candidate := append([]int{}, order...)
+				for a, b := i, j; a < b; a, b = a+1, b-1 {
+					candidate[a], candidate[b] = candidate[b], candidate[a]
+				}
+				if c := tourCost(cost, candidate, ret); c < best {
+					best, order, improved = c, candidate, true
+				}
+			}
+		}
+	}
+	return order
+}
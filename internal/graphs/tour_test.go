@@ -0,0 +1,87 @@
+package graphs
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanTour(t *testing.T) {
+	g := seedGraph()
+
+	tour, err := g.PlanTour("A", []string{"C"}, TourOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, TourHeldKarp, tour.Algorithm)
+	assert.Equal(t, []string{"A", "C"}, tour.Order)
+	assert.Equal(t, []string{"A", "B", "C"}, tour.Path)
+	assert.Equal(t, 9, tour.Distance)
+
+	// C-D-E-B-C visits every town and returns: 8+6+3+4
+	tour, err = g.PlanTour("C", []string{"B", "D", "E"}, TourOptions{Return: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"C", "D", "E", "B", "C"}, tour.Order)
+	assert.Equal(t, 21, tour.Distance)
+
+	// nothing leads back to A
+	_, err = g.PlanTour("A", []string{"C"}, TourOptions{Return: true})
+	assert.ErrorIs(t, err, ErrNoSuchRoute)
+
+	tooMany := MaxExactTourLimit + 1
+	_, err = g.PlanTour("A", []string{"C"}, TourOptions{ExactLimit: &tooMany})
+	assert.EqualError(t, err, "exact limit must be between 0 and 16")
+
+	// a limit of zero never solves exactly, even for one town
+	never := 0
+	tour, err = g.PlanTour("A", []string{"C"}, TourOptions{ExactLimit: &never})
+	assert.NoError(t, err)
+	assert.Equal(t, TourNearestNeighbor, tour.Algorithm)
+	assert.Equal(t, 9, tour.Distance)
+}
+
+func TestPlanTourHeuristicUnreachable(t *testing.T) {
+	unreachable := math.MaxInt
+	// from 0 only 2 is reachable, and from 2 nothing is left to reach
+	assert.Equal(t, []int{0, 2, 1}, nearestNeighbour([][]int{{0, unreachable, 5}, {1, 0, 1}, {1, 2, 0}}))
+	assert.Nil(t, nearestNeighbour([][]int{{0, 3, 5}, {1, 0, unreachable}, {1, 2, 0}}))
+
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB1", "BC1", "XY1"}))
+	never := 0
+	_, err := g.PlanTour("A", []string{"B", "C", "X"}, TourOptions{ExactLimit: &never})
+	assert.ErrorIs(t, err, ErrNoSuchRoute)
+}
+
+func TestPlanTourHeuristicMatchesMetric(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	towns := []string{"A", "B", "C", "D", "E", "F", "G"}
+	var edges []string
+	for _, from := range towns {
+		for _, to := range towns {
+			if from != to {
+				edges = append(edges, fmt.Sprintf("%s%s%d", from, to, 1+rng.Intn(20)))
+			}
+		}
+	}
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges(edges))
+
+	exact, err := g.PlanTour("A", towns[1:], TourOptions{Return: true})
+	assert.NoError(t, err)
+	assert.Equal(t, TourHeldKarp, exact.Algorithm)
+
+	limit := 3
+	heuristic, err := g.PlanTour("A", towns[1:], TourOptions{Return: true, ExactLimit: &limit})
+	assert.NoError(t, err)
+	assert.Equal(t, TourNearestNeighbor, heuristic.Algorithm)
+	assert.GreaterOrEqual(t, heuristic.Distance, exact.Distance)
+
+	for _, tour := range []Tour{exact, heuristic} {
+		assert.Len(t, tour.Order, len(towns)+1)
+		d, err := g.Distance(tour.Path)
+		assert.NoError(t, err)
+		assert.Equal(t, tour.Distance, d)
+	}
+}
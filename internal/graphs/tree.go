@@ -0,0 +1,75 @@
+package graphs
+
+import (
+	"sort"
+	"time"
+)
+
+// TreeNode is one town of a shortest-path tree.
+type TreeNode struct {
+	Town     string `json:"town"`
+	Distance int    `json:"distance"`
+	// Predecessor is the town the shortest route arrives from; empty for
+	// the root.
+	Predecessor string   `json:"predecessor,omitempty"`
+	Path        []string `json:"path,omitempty"`
+}
+
+// ShortestPathTree holds the shortest route from one town to every town it
+// can reach, ordered by distance and then by name, starting with the root.
+type ShortestPathTree struct {
+	From  string     `json:"from"`
+	Towns []TreeNode `json:"towns"`
+}
+
+// ShortestPathTree runs Dijkstra once from the given town and returns the
+// distance and predecessor of every reachable town, with the full path to
+// each when withPaths is set. It reports false for an unknown town.
+func (g *Graph) ShortestPathTree(from string, withPaths bool) (ShortestPathTree, bool) {
+	return g.ShortestPathTreeWithStats(from, withPaths, nil)
+}
+
+// ShortestPathTreeWithStats is ShortestPathTree recording the work of the
+// search in stats, which may be nil.
+func (g *Graph) ShortestPathTreeWithStats(from string, withPaths bool, stats *SearchStats) (ShortestPathTree, bool) {
+	defer stats.timed(time.Now())
+	if stats != nil {
+		stats.Algorithm = AlgorithmDijkstra
+	}
+	c := g.snapshot()
+	src, ok := c.id(from)
+	if !ok {
+		return ShortestPathTree{}, false
+	}
+	dist, parent := dijkstraFrom(c, src, stats)
+
+	tree := ShortestPathTree{From: from, Towns: []TreeNode{}}
+	for v, d := range dist {
+		if d < 0 {
+			continue
+		}
+		node := TreeNode{Town: c.names[v], Distance: d}
+		if v != src {
+			node.Predecessor = c.names[parent[v]]
+		}
+		if withPaths {
+			ids := []int{v}
+			for cur := v; cur != src; {
+				cur = int(parent[cur])
+				ids = append(ids, cur)
+			}
+			node.Path = make([]string, len(ids))
+			for i, id := range ids {
+				node.Path[len(ids)-1-i] = c.names[id]
+			}
+		}
+		tree.Towns = append(tree.Towns, node)
+	}
+	sort.Slice(tree.Towns, func(i, j int) bool {
+		if tree.Towns[i].Distance != tree.Towns[j].Distance {
+			return tree.Towns[i].Distance < tree.Towns[j].Distance
+		}
+		return tree.Towns[i].Town < tree.Towns[j].Town
+	})
+	return tree, true
+}
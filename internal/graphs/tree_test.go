@@ -0,0 +1,44 @@
+package graphs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShortestPathTree(t *testing.T) {
+	g := seedGraph()
+	tree, ok := g.ShortestPathTree("A", true)
+	assert.True(t, ok)
+	assert.Equal(t, "A", tree.From)
+	assert.Equal(t, []TreeNode{
+		{Town: "A", Distance: 0, Path: []string{"A"}},
+		{Town: "B", Distance: 5, Predecessor: "A", Path: []string{"A", "B"}},
+		{Town: "D", Distance: 5, Predecessor: "A", Path: []string{"A", "D"}},
+		{Town: "E", Distance: 7, Predecessor: "A", Path: []string{"A", "E"}},
+		{Town: "C", Distance: 9, Predecessor: "B", Path: []string{"A", "B", "C"}},
+	}, tree.Towns)
+}
+
+func TestShortestPathTreeMatchesShortestPath(t *testing.T) {
+	g := seedGraph()
+	tree, ok := g.ShortestPathTree("C", false)
+	assert.True(t, ok)
+	// A is not reachable from C
+	assert.Len(t, tree.Towns, 4)
+	for _, n := range tree.Towns {
+		assert.Nil(t, n.Path)
+		if n.Town == "C" {
+			assert.Equal(t, 0, n.Distance)
+			continue
+		}
+		dist, path := g.ShortestPath("C", n.Town)
+		assert.Equal(t, dist, n.Distance)
+		assert.Equal(t, path[len(path)-2], n.Predecessor)
+	}
+}
+
+func TestShortestPathTreeUnknownTown(t *testing.T) {
+	_, ok := seedGraph().ShortestPathTree("Z", false)
+	assert.False(t, ok)
+}
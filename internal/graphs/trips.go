@@ -0,0 +1,161 @@
+package graphs
+
+const (
+	// DefaultTripLimit is the number of trips the counters return when
+	// asked for them without a limit.
+	DefaultTripLimit = 100
+	// MaxTripLimit bounds the trips returned by a single count.
+	MaxTripLimit = 10000
+)
+
+// Trip is one trip found by a counter.
+type Trip struct {
+	Path     []string `json:"path"`
+	Distance int      `json:"distance"`
+	Stops    int      `json:"stops"`
+}
+
+// TripList holds the trips collected alongside a count. Truncated is set
+// when the count is larger than the number of trips listed.
+type TripList struct {
+	Trips     []Trip `json:"trips"`
+	Truncated bool   `json:"truncated"`
+}
+
+// tripStep is a town on a partial trip, pointing at the step before it so
+// that trips share their prefixes.
+type tripStep struct {
+	node int32
+	prev int32
+}
+
+// tripCollector records the first limit trips of one branch of a count. A
+// nil collector records nothing.
+//
+// The trail holds the steps of the DFS stack and their ancestors only: a
+// state is popped after every state pushed after it, so popping the state
+// at step s leaves nothing above s alive and the trail is cut back to it.
+// Recorded trips are copied out of the trail as they are found.
+type tripCollector struct {
+	limit int
+	trail []tripStep
+	paths [][]int32
+}
+
+// newTripCollector returns a collector when opts asks for trips.
+func newTripCollector(opts EnumOptions) *tripCollector {
+	if opts.Trips == nil {
+		return nil
+	}
+	return &tripCollector{limit: opts.tripLimit()}
+}
+
+// full reports whether no more trips will be recorded.
+func (tc *tripCollector) full() bool {
+	return tc == nil || len(tc.paths) >= tc.limit
+}
+
+// step extends the trail with node after step prev, returning the new step
+// or -1 once no trip through it could be recorded. A nil collector never
+// hands out a step, so the check on prev keeps counting without trips on
+// the inlined fast path.
+func (tc *tripCollector) step(node, prev int32) int32 {
+	if prev < 0 {
+		return -1
+	}
+	return tc.extend(node, prev)
+}
+
+func (tc *tripCollector) extend(node, prev int32) int32 {
+	if tc.full() {
+		return -1
+	}
+	tc.trail = append(tc.trail, tripStep{node: node, prev: prev})
+	return int32(len(tc.trail) - 1)
+}
+
+// pop tells the collector the DFS popped the state at step s, dropping the
+// steps pushed after it. Like step, it is a no-op without a step.
+func (tc *tripCollector) pop(s int32) {
+	if s < 0 {
+		return
+	}
+	tc.truncate(s)
+}
+
+func (tc *tripCollector) truncate(s int32) {
+	if tc.full() {
+		return
+	}
+	tc.trail = tc.trail[:s+1]
+}
+
+// root starts the trail at the origin.
+func (tc *tripCollector) root(node int32) int32 {
+	if tc.full() {
+		return -1
+	}
+	tc.trail = append(tc.trail, tripStep{node: node, prev: -1})
+	return int32(len(tc.trail) - 1)
+}
+
+// record notes a counted trip ending at step s, copying its towns, last
+// first, out of the trail. The trail is dropped once the collector is full.
+func (tc *tripCollector) record(s int32) {
+	if tc.full() || s < 0 {
+		return
+	}
+	var ids []int32
+	for ; s >= 0; s = tc.trail[s].prev {
+		ids = append(ids, tc.trail[s].node)
+	}
+	tc.paths = append(tc.paths, ids)
+	if tc.full() {
+		tc.trail = nil
+	}
+}
+
+// trips materialises the recorded trips, recovering distance and stops
+// from the towns so the traversal state stays small.
+func (tc *tripCollector) trips(c *csr) []Trip {
+	if tc == nil {
+		return nil
+	}
+	out := make([]Trip, len(tc.paths))
+	for k, ids := range tc.paths {
+		trip := Trip{Path: make([]string, len(ids)), Stops: len(ids) - 1}
+		for i, v := range ids {
+			trip.Path[len(ids)-1-i] = c.names[v]
+			if i > 0 {
+				slot, _ := c.find(int(v), int(ids[i-1]))
+				trip.Distance += c.dists[slot]
+			}
+		}
+		out[k] = trip
+	}
+	return out
+}
+
+// tripLimit returns the number of trips to collect.
+func (opts EnumOptions) tripLimit() int {
+	if opts.TripLimit <= 0 {
+		return DefaultTripLimit
+	}
+	return min(opts.TripLimit, MaxTripLimit)
+}
+
+// mergeTrips fills opts.Trips from the root trip, if counted, and the
+// branches in order, keeping the first tripLimit.
+func (opts EnumOptions) mergeTrips(c *csr, root []Trip, branches []*tripCollector, total int) {
+	if opts.Trips == nil {
+		return
+	}
+	trips := append([]Trip{}, root...)
+	for _, tc := range branches {
+		trips = append(trips, tc.trips(c)...)
+	}
+	if limit := opts.tripLimit(); len(trips) > limit {
+		trips = trips[:limit]
+	}
+	*opts.Trips = TripList{Trips: trips, Truncated: total > len(trips)}
+}
@@ -0,0 +1,120 @@
+package graphs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountTripsByStopsIncludesTrips(t *testing.T) {
+	g := seedGraph()
+	var list TripList
+	count, err := g.CountTripsByStopsContext(context.Background(), "C", "C", 1, 3, EnumOptions{Trips: &list})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.False(t, list.Truncated)
+	assert.ElementsMatch(t, []Trip{
+		{Path: []string{"C", "D", "C"}, Distance: 16, Stops: 2},
+		{Path: []string{"C", "E", "B", "C"}, Distance: 9, Stops: 3},
+	}, list.Trips)
+
+	count, err = g.CountTripsByStopsContext(context.Background(), "C", "C", 1, 3, EnumOptions{Trips: &list, TripLimit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.True(t, list.Truncated)
+	assert.Len(t, list.Trips, 1)
+
+	count, err = g.CountTripsByStopsContext(context.Background(), "A", "A", 0, 2, EnumOptions{Trips: &list})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []Trip{{Path: []string{"A"}}}, list.Trips)
+}
+
+func TestCountTripsByDistanceIncludesTrips(t *testing.T) {
+	g := seedGraph()
+	var list TripList
+	count, err := g.CountTripsByDistanceContext(context.Background(), "C", "C", 30, EnumOptions{Trips: &list})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, count)
+	assert.False(t, list.Truncated)
+	assert.Len(t, list.Trips, 7)
+	for _, trip := range list.Trips {
+		d, err := g.Distance(trip.Path)
+		assert.NoError(t, err)
+		assert.Equal(t, d, trip.Distance)
+		assert.Less(t, trip.Distance, 30)
+		assert.Equal(t, len(trip.Path)-1, trip.Stops)
+		assert.Equal(t, "C", trip.Path[0])
+		assert.Equal(t, "C", trip.Path[len(trip.Path)-1])
+	}
+}
+
+func TestIncludedTripsIndependentOfWorkers(t *testing.T) {
+	g := denseGraph(t)
+	ctx := context.Background()
+	var seq, par TripList
+	want, err := g.CountTripsByDistanceContext(ctx, "A", "Z", 40, EnumOptions{Trips: &seq, TripLimit: 50})
+	assert.NoError(t, err)
+	got, err := g.CountTripsByDistanceContext(ctx, "A", "Z", 40, EnumOptions{Workers: 4, Trips: &par, TripLimit: 50})
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, seq, par)
+	assert.Len(t, seq.Trips, 50)
+	assert.True(t, seq.Truncated)
+
+	seq, par = TripList{}, TripList{}
+	want, err = g.CountTripsByStopsContext(ctx, "A", "Z", 1, 3, EnumOptions{Trips: &seq})
+	assert.NoError(t, err)
+	got, err = g.CountTripsByStopsContext(ctx, "A", "Z", 1, 3, EnumOptions{Workers: 4, Trips: &par})
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, seq, par)
+	assert.Len(t, seq.Trips, min(want, DefaultTripLimit))
+}
+
+func TestTripCollectorKeepsOnlyLivePath(t *testing.T) {
+	// a complete graph on 6 towns enumerated to 8 stops visits millions of
+	// states; only the trips to the one town never reached are asked for
+	g := NewGraph()
+	var edges []string
+	for _, from := range "ABCDEF" {
+		for _, to := range "ABCDEF" {
+			if from != to {
+				edges = append(edges, string(from)+string(to)+"1")
+			}
+		}
+	}
+	assert.NoError(t, g.LoadEdges(append(edges, "ZA1")))
+	c := g.snapshot()
+	src, _ := c.id("A")
+	const maxStops = 8
+
+	// the same pushes and pops as CountTripsByStopsContext
+	type state struct {
+		node  int32
+		stops int
+		step  int32
+	}
+	tc := newTripCollector(EnumOptions{Trips: &TripList{}})
+	stack := []state{{int32(src), 0, tc.root(int32(src))}}
+	peak, states := 0, 0
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		tc.pop(n.step)
+		states++
+		if n.stops == maxStops {
+			continue
+		}
+		lo, hi := c.out(int(n.node))
+		for i := lo; i < hi; i++ {
+			stack = append(stack, state{c.targets[i], n.stops + 1, tc.step(c.targets[i], n.step)})
+		}
+		peak = max(peak, len(tc.trail))
+	}
+	assert.Greater(t, states, 100000)
+	// the live path plus the pending siblings at every depth
+	assert.LessOrEqual(t, peak, 1+maxStops*5)
+	assert.Empty(t, tc.paths)
+}
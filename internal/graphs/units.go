@@ -0,0 +1,174 @@
+package graphs
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxDecimals is the most decimal places a distance may have.
+const MaxDecimals = 3
+
+// distanceUnit is a unit a graph may declare with @units, measured in the
+// base unit of its kind: metres for lengths, seconds for times.
+type distanceUnit struct {
+	kind string
+	base float64
+}
+
+var distanceUnits = map[string]distanceUnit{
+	"m":   {"length", 1},
+	"km":  {"length", 1000},
+	"mi":  {"length", 1609.344},
+	"s":   {"time", 1},
+	"min": {"time", 60},
+	"h":   {"time", 3600},
+}
+
+// unitAliases are the other names accepted for units.
+var unitAliases = map[string]string{
+	"minutes": "min",
+	"hours":   "h",
+	"miles":   "mi",
+}
+
+// ParseUnit returns the canonical name of a distance unit.
+func ParseUnit(s string) (string, error) {
+	u := strings.ToLower(strings.TrimSpace(s))
+	if alias, ok := unitAliases[u]; ok {
+		u = alias
+	}
+	if _, ok := distanceUnits[u]; !ok {
+		return "", fmt.Errorf("unknown unit %q, want one of %s", s, strings.Join(Units(), ", "))
+	}
+	return u, nil
+}
+
+// Units returns the canonical names of the distance units, sorted.
+func Units() []string {
+	names := make([]string, 0, len(distanceUnits))
+	for u := range distanceUnits {
+		names = append(names, u)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseDistance reads a decimal distance of at most MaxDecimals places as a
+// count of 1/scale, scale being 10 to the number of places.
+func parseDistance(s string) (value, scale int, err error) {
+	whole, frac, dotted := strings.Cut(strings.TrimSpace(s), ".")
+	if dotted && (frac == "" || frac[0] == '-' || frac[0] == '+') {
+		return 0, 0, ErrInvalidDistance
+	}
+	if len(frac) > MaxDecimals {
+		return 0, 0, fmt.Errorf("%w: more than %d decimal places", ErrInvalidDistance, MaxDecimals)
+	}
+	value, err = strconv.Atoi(whole + frac)
+	if err != nil {
+		return 0, 0, ErrInvalidDistance
+	}
+	scale = 1
+	for range frac {
+		scale *= 10
+	}
+	return value, scale, nil
+}
+
+// measuredDistance returns a measured distance x, rounded to MaxDecimals
+// places but at least one step of them, as a count of 1/scale with the
+// fewest places that hold it exactly.
+func measuredDistance(x float64) (value, scale int) {
+	scale = 1
+	for range MaxDecimals {
+		scale *= 10
+	}
+	value = max(1, int(math.Round(x*float64(scale))))
+	for scale > 1 && value%10 == 0 {
+		value, scale = value/10, scale/10
+	}
+	return value, scale
+}
+
+// formatDistance writes a count of 1/scale as a decimal, without trailing
+// zeros.
+func formatDistance(value, scale int) string {
+	if scale <= 1 {
+		return strconv.Itoa(value)
+	}
+	return strconv.FormatFloat(float64(value)/float64(scale), 'f', -1, 64)
+}
+
+// UnitConverter turns the distances of a graph, stored as counts of
+// 1/DistanceScale of its declared unit, into another unit and back.
+type UnitConverter struct {
+	// Unit is the unit converted to, empty when the graph declares none
+	// and none was asked for.
+	Unit   string
+	scale  int
+	factor float64
+}
+
+// Identity reports whether the converter leaves stored distances as they
+// are.
+func (u UnitConverter) Identity() bool {
+	return u.scale == 1 && u.factor == 1
+}
+
+// Value returns a stored distance in the converter's unit. Values in the
+// declared unit are exact; converted ones are rounded to MaxDecimals places.
+func (u UnitConverter) Value(d int) float64 {
+	v := float64(d) / float64(u.scale)
+	if u.factor == 1 {
+		return v
+	}
+	const round = 1000 // 10^MaxDecimals
+	return math.Round(v*u.factor*round) / round
+}
+
+// Stored returns a distance in the converter's unit as a count of stored
+// steps, which is fractional when it falls between two steps.
+func (u UnitConverter) Stored(v float64) float64 {
+	steps := v / u.factor * float64(u.scale)
+	// drop the error of the conversion so that limits on a step stay on it
+	return math.Round(steps*1e6) / 1e6
+}
+
+// DistanceScale returns how many stored distance steps make one unit of the
+// current graph. Distances are stored as integers so that sums stay exact;
+// the scale is 1 unless some distance has decimals, and distances returned
+// by Graph methods are in steps.
+func (g *Graph) DistanceScale() int {
+	return g.snapshot().scale
+}
+
+// Converter returns a converter from the distances of the current graph to
+// unit, which must be of the same kind as the unit the graph declares. An
+// empty unit keeps the declared one.
+func (g *Graph) Converter(unit string) (UnitConverter, error) {
+	g.mutex.RLock()
+	declared, scale := g.meta.Units, g.data.scale
+	g.mutex.RUnlock()
+	conv := UnitConverter{Unit: declared, scale: scale, factor: 1}
+	if unit == "" {
+		return conv, nil
+	}
+	to, err := ParseUnit(unit)
+	if err != nil {
+		return conv, err
+	}
+	if declared == "" {
+		return conv, fmt.Errorf("graph declares no units to convert to %s", to)
+	}
+	from, want := distanceUnits[declared], distanceUnits[to]
+	if from.kind != want.kind {
+		return conv, fmt.Errorf("cannot convert %s to %s", declared, to)
+	}
+	conv.Unit = to
+	if to != declared {
+		conv.factor = from.base / want.base
+	}
+	return conv, nil
+}
@@ -0,0 +1,151 @@
+package graphs
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDistance(t *testing.T) {
+	for _, tt := range []struct {
+		in           string
+		value, scale int
+	}{
+		{"12", 12, 1},
+		{"12.5", 125, 10},
+		{"0.125", 125, 1000},
+		{"3.50", 350, 100},
+	} {
+		value, scale, err := parseDistance(tt.in)
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.value, value, tt.in)
+		assert.Equal(t, tt.scale, scale, tt.in)
+	}
+	for _, in := range []string{"", "1.", ".", "1.-5", "1.2345", "1e3", "x"} {
+		_, _, err := parseDistance(in)
+		assert.True(t, errors.Is(err, ErrInvalidDistance), in)
+	}
+
+	for _, tt := range []struct {
+		in           float64
+		value, scale int
+	}{
+		{55, 55, 1},
+		{30.0166, 30017, 1000},
+		{2.5, 25, 10},
+		{0, 1, 1000},
+	} {
+		value, scale := measuredDistance(tt.in)
+		assert.Equal(t, tt.value, value, tt.in)
+		assert.Equal(t, tt.scale, scale, tt.in)
+	}
+
+	assert.Equal(t, "12", formatDistance(12, 1))
+	assert.Equal(t, "12.5", formatDistance(12500, 1000))
+	assert.Equal(t, "0.125", formatDistance(125, 1000))
+}
+
+func TestLoadDecimalDistances(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB2.5", "BC1.25", "A->C:4", "CD0.125/3"}))
+	assert.Equal(t, 1000, g.DistanceScale())
+	assert.Equal(t, []EdgeSpec{
+		{From: "A", To: "B", Distance: 2500, Scale: 1000},
+		{From: "A", To: "C", Distance: 4000, Scale: 1000},
+		{From: "B", To: "C", Distance: 1250, Scale: 1000},
+		{From: "C", To: "D", Distance: 125, Scale: 1000, Capacity: 3},
+	}, g.EdgeList())
+
+	// sums stay exact where floats would not: 0.1 + 0.2 is 0.3
+	assert.NoError(t, g.LoadEdges([]string{"AB0.1", "BC0.2"}))
+	dist, err := g.Distance([]string{"A", "B", "C"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, dist)
+	assert.Equal(t, 10, g.DistanceScale())
+
+	assert.NoError(t, g.LoadEdges([]string{"AB5", "BC4"}))
+	assert.Equal(t, 1, g.DistanceScale())
+	assert.Equal(t, []EdgeSpec{{From: "A", To: "B", Distance: 5}, {From: "B", To: "C", Distance: 4}}, g.EdgeList())
+}
+
+func TestImportDecimalDistances(t *testing.T) {
+	inputs := map[string]string{
+		FormatCSV:  "from,to,distance\nA,B,2.5\nB,C,1\n",
+		FormatJSON: `[{"from":"A","to":"B","distance":2.5},{"from":"B","to":"C","distance":1}]`,
+		FormatDOT:  `digraph { A -> B [distance=2.5]; B -> C [weight=1] }`,
+	}
+	for format, input := range inputs {
+		g := NewGraph()
+		assert.NoError(t, g.Import(strings.NewReader(input), format), format)
+		dist, path := g.ShortestPath("A", "C")
+		assert.Equal(t, 35, dist, format)
+		assert.Equal(t, []string{"A", "B", "C"}, path, format)
+	}
+
+	g := NewGraph()
+	err := g.Import(strings.NewReader(`[{"from":"A","to":"B","distance":2.5001}]`), FormatJSON)
+	assert.True(t, errors.Is(err, ErrInvalidDistance))
+}
+
+func TestExportDecimalDistances(t *testing.T) {
+	g := NewGraph()
+	assert.NoError(t, g.LoadEdges([]string{"AB2.5", "BC1"}))
+	for _, format := range []string{FormatText, FormatCSV, FormatDOT, FormatGraphML} {
+		var buf bytes.Buffer
+		assert.NoError(t, g.Export(&buf, format), format)
+		again := NewGraph()
+		assert.NoError(t, again.Import(&buf, format), format)
+		assert.Equal(t, g.EdgeList(), again.EdgeList(), format)
+	}
+}
+
+func TestConverter(t *testing.T) {
+	g := NewGraph()
+	_, err := g.ImportWithOptions(strings.NewReader("@units km\nAB2.5, BC1.25"), FormatText, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, Metadata{Units: "km"}, g.Metadata())
+
+	conv, err := g.Converter("")
+	assert.NoError(t, err)
+	assert.Equal(t, "km", conv.Unit)
+	assert.Equal(t, 3.75, conv.Value(375))
+	assert.Equal(t, 25.0, conv.Stored(0.25))
+
+	conv, err = g.Converter("miles")
+	assert.NoError(t, err)
+	assert.Equal(t, "mi", conv.Unit)
+	assert.Equal(t, 1.553, conv.Value(250))
+	assert.Equal(t, 402.336, conv.Stored(2.5))
+
+	conv, err = g.Converter("m")
+	assert.NoError(t, err)
+	assert.Equal(t, 2500.0, conv.Value(250))
+	// 1 m is exactly 1/10 of a step
+	assert.Equal(t, 0.1, conv.Stored(1))
+
+	_, err = g.Converter("min")
+	assert.EqualError(t, err, "cannot convert km to min")
+	_, err = g.Converter("furlong")
+	assert.Error(t, err)
+
+	assert.NoError(t, g.LoadEdges([]string{"AB5"}))
+	conv, err = g.Converter("")
+	assert.NoError(t, err)
+	assert.True(t, conv.Identity())
+	assert.Equal(t, "", conv.Unit)
+	_, err = g.Converter("km")
+	assert.EqualError(t, err, "graph declares no units to convert to km")
+}
+
+func TestImportTextUnits(t *testing.T) {
+	g := NewGraph()
+	_, err := g.ImportWithOptions(strings.NewReader("@units Minutes\nAB5"), FormatText, ImportOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "min", g.Metadata().Units)
+
+	_, err = g.ImportWithOptions(strings.NewReader("@units parsecs\nAB5"), FormatText, ImportOptions{})
+	assert.True(t, errors.Is(err, ErrInvalidDirective))
+}
@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"mime"
 	"net/http"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
-	"unicode"
+	"time"
 
 	graph "github.com/aashi1008/hamburg-rails/internal/graphs"
 	"github.com/aashi1008/hamburg-rails/internal/metrics"
@@ -18,6 +24,8 @@ var townRegex = regexp.MustCompile(`^[A-Z]{1,16}$`)
 
 type Handler struct {
 	Graph *graph.Graph
+	// Enum configures route enumeration for the count and search endpoints.
+	Enum graph.EnumOptions
 }
 
 func NewHandler(g *graph.Graph) *Handler {
@@ -33,50 +41,324 @@ func writeJSON(w http.ResponseWriter, v interface{}) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// explainStats parses the explain query parameter accepted by the route
+// endpoints, returning statistics to fill in when it is set and nil
+// otherwise.
+func explainStats(r *http.Request) (*graph.SearchStats, error) {
+	raw := r.URL.Query().Get("explain")
+	if raw == "" {
+		return nil, nil
+	}
+	on, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("explain must be true or false")
+	}
+	if !on {
+		return nil, nil
+	}
+	return &graph.SearchStats{}, nil
+}
+
+// writeExplained writes v, which must encode as a JSON object, with the
+// search statistics added under "explain" when stats is not nil.
+func writeExplained(w http.ResponseWriter, v interface{}, stats *graph.SearchStats) {
+	if stats == nil {
+		writeJSON(w, v)
+		return
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	fields["explain"], _ = json.Marshal(stats)
+	writeJSON(w, fields)
+}
+
+// distanceUnit parses the unit query parameter accepted by the endpoints
+// that return or take distances.
+func (h *Handler) distanceUnit(r *http.Request) (graph.UnitConverter, error) {
+	return h.Graph.Converter(r.URL.Query().Get("unit"))
+}
+
+// noUnit refuses the unit query parameter on endpoints that do not convert
+// distances: flows and critical elements have none, and graph uploads report
+// them in the uploaded graph's own unit, matching its lines. It writes the
+// error response and returns false when unit is given.
+func noUnit(w http.ResponseWriter, r *http.Request) bool {
+	if r.URL.Query().Has("unit") {
+		writeError(w, http.StatusUnprocessableEntity, "unit is not supported by this endpoint")
+		return false
+	}
+	return true
+}
+
+// storedDistance converts a maxDistance given in the unit of conv to whole
+// stored steps, rounding up.
+func storedDistance(conv graph.UnitConverter, v float64) (int, error) {
+	stored := math.Ceil(conv.Stored(v))
+	if stored >= 1<<62 {
+		return 0, errors.New("maxDistance is too large")
+	}
+	return int(stored), nil
+}
+
+// distanceKeys are the fields holding distances in responses.
+var distanceKeys = map[string]bool{"distance": true, "Distance": true, "totalDistance": true}
+
+// writeDistances is writeExplained for responses holding distances, which
+// it converts from stored steps with conv, adding the unit under "unit"
+// when there is one.
+func writeDistances(w http.ResponseWriter, v interface{}, conv graph.UnitConverter, stats *graph.SearchStats) {
+	if conv.Identity() && conv.Unit == "" {
+		writeExplained(w, v, stats)
+		return
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	convertDistances(fields, conv)
+	if conv.Unit != "" {
+		fields["unit"] = conv.Unit
+	}
+	writeExplained(w, fields, stats)
+}
+
+// convertDistances rewrites the distanceKeys fields of a decoded JSON value
+// in place.
+func convertDistances(v interface{}, conv graph.UnitConverter) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if n, ok := field.(json.Number); ok && distanceKeys[k] {
+				if d, err := n.Int64(); err == nil {
+					v[k] = conv.Value(int(d))
+				}
+				continue
+			}
+			convertDistances(field, conv)
+		}
+	case []interface{}:
+		for _, item := range v {
+			convertDistances(item, conv)
+		}
+	}
+}
+
 func writeError(w http.ResponseWriter, status int, msg string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
 
-func sanitizeEdgesInput(raw string) []string {
-	parts := strings.Split(raw, ",")
-	edges := make([]string, 0, len(parts))
-	for _, p := range parts {
-		e := strings.TrimFunc(p, func(r rune) bool {
-			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
-		})
-		e = strings.TrimSpace(e)
-		if e != "" {
-			edges = append(edges, e)
-		}
+// writeGraphError reports a failed graph load, listing every problem with
+// its position under "errors" when the input had invalid edges.
+func writeGraphError(w http.ResponseWriter, err error) {
+	var errs graph.ParseErrors
+	if !errors.As(err, &errs) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("graph parse error: %v", err))
+		return
+	}
+	msg := fmt.Sprintf("graph parse error: %d invalid edges", len(errs))
+	if len(errs) == 1 {
+		msg = fmt.Sprintf("graph parse error: %v", errs[0])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	enc := json.NewEncoder(w)
+	// keep the A->B in messages readable
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(map[string]interface{}{"error": msg, "errors": errs})
+}
+
+// readGraphUpload returns the body of a graph upload along with its file
+// name and content type. Multipart forms carry the graph in the "file" field.
+func readGraphUpload(r *http.Request) ([]byte, string, string, error) {
+	contentType := r.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "multipart/form-data" {
+		data, err := io.ReadAll(r.Body)
+		return data, "", contentType, err
 	}
-	return edges
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	return data, header.Filename, header.Header.Get("Content-Type"), err
 }
 
-func (h *Handler) LoadGraph(w http.ResponseWriter, r *http.Request) {
-	data, err := io.ReadAll(r.Body)
+// graphImport reads the graph and import options of a load or validate
+// request. It writes the error response and returns false when the request
+// is invalid.
+func graphImport(w http.ResponseWriter, r *http.Request) ([]byte, string, graph.ImportOptions, bool) {
+	var opts graph.ImportOptions
+	if !noUnit(w, r) {
+		return nil, "", opts, false
+	}
+	data, filename, contentType, err := readGraphUpload(r)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return nil, "", opts, false
+	}
+	// an explicit format wins over the file extension and content type
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = graph.ImportFormat(filename, contentType)
+	}
+	if format == "" {
+		format = graph.FormatText
+	}
+	if !slices.Contains(graph.ImportFormats(), format) {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("unsupported graph format: %q", format))
+		return nil, "", opts, false
+	}
+	if raw := r.URL.Query().Get("mirror"); raw != "" {
+		if opts.Mirror, err = strconv.ParseBool(raw); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "mirror must be true or false")
+			return nil, "", opts, false
+		}
+	}
+	return data, format, opts, true
+}
+
+func (h *Handler) LoadGraph(w http.ResponseWriter, r *http.Request) {
+	data, format, opts, ok := graphImport(w, r)
+	if !ok {
 		return
 	}
-	edges := sanitizeEdgesInput(string(data))
-	if err := h.Graph.LoadEdges(edges); err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("graph parse error: %v", err))
+	if raw := r.URL.Query().Get("dryRun"); raw != "" {
+		var err error
+		if opts.DryRun, err = strconv.ParseBool(raw); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "dryRun must be true or false")
+			return
+		}
+	}
+	if opts.DryRun {
+		h.validateGraph(w, data, format, opts)
+		return
+	}
+	report, err := h.Graph.ImportWithOptions(bytes.NewReader(data), format, opts)
+	if err != nil {
+		writeGraphError(w, err)
 		return
 	}
-	nodes := len(h.Graph.Nodes)
+	nodes := h.Graph.NodeCount()
 	metrics.GraphLoadsTotal.Inc()
 	metrics.GraphNodesTotal.Set(float64(nodes))
-	writeJSON(w, map[string]string{"status": "ok", "message": "graph loaded"})
+	writeJSON(w, struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		graph.LoadReport
+	}{"ok", "graph loaded", report})
+}
+
+// ValidateGraph checks a graph upload as LoadGraph does, without loading it.
+func (h *Handler) ValidateGraph(w http.ResponseWriter, r *http.Request) {
+	data, format, opts, ok := graphImport(w, r)
+	if !ok {
+		return
+	}
+	opts.DryRun = true
+	h.validateGraph(w, data, format, opts)
+}
+
+// validateGraph writes the report of a dry-run load. Invalid edges are
+// listed under "errors" alongside the report on the rest; input that cannot
+// be read at all is a 400 as for a load.
+func (h *Handler) validateGraph(w http.ResponseWriter, data []byte, format string, opts graph.ImportOptions) {
+	report, err := h.Graph.ImportWithOptions(bytes.NewReader(data), format, opts)
+	var errs graph.ParseErrors
+	if err != nil && !errors.As(err, &errs) {
+		writeGraphError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	// keep the A->B in messages readable
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(struct {
+		Valid  bool              `json:"valid"`
+		Errors graph.ParseErrors `json:"errors,omitempty"`
+		graph.LoadReport
+	}{len(errs) == 0, errs, report})
 }
 
+// CurrentEdgeList writes the graph as JSON, or in the export format given by
+// ?format= or negotiated from the Accept header.
 func (h *Handler) CurrentEdgeList(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = graph.NegotiateExportFormat(r.Header.Get("Accept"))
+	}
+	if format != "" && format != graph.FormatJSON {
+		mediaType, ok := graph.ExportMediaType(format)
+		if !ok {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("unsupported graph format: %q", format))
+			return
+		}
+		var buf bytes.Buffer
+		if err := h.Graph.Export(&buf, format); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", mediaType)
+		_, _ = w.Write(buf.Bytes())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
 	type item struct {
-		Edges map[string][]graph.Edge `json:"edges"`
-		Count int                     `json:"node_count"`
+		Edges       map[string][]graph.Edge     `json:"edges"`
+		Count       int                         `json:"node_count"`
+		Coordinates map[string]graph.Coordinate `json:"coordinates,omitempty"`
+		Metadata    *graph.Metadata             `json:"metadata,omitempty"`
+	}
+	edges := h.Graph.Nodes()
+	res := &item{Edges: edges, Count: len(edges), Coordinates: h.Graph.Coordinates()}
+	if meta := h.Graph.Metadata(); meta != (graph.Metadata{}) {
+		res.Metadata = &meta
+	}
+	writeDistances(w, res, conv, nil)
+}
+
+func (h *Handler) SetCoordinates(w http.ResponseWriter, r *http.Request) {
+	var coords map[string]graph.Coordinate
+	if err := json.NewDecoder(r.Body).Decode(&coords); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	normalized := make(map[string]graph.Coordinate, len(coords))
+	for town, c := range coords {
+		t, err := validateTown(town)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		normalized[t] = c
+	}
+	if err := h.Graph.SetCoordinates(normalized); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
 	}
-	writeJSON(w, &item{Edges: h.Graph.Nodes, Count: len(h.Graph.Nodes)})
+	writeJSON(w, map[string]string{"status": "ok", "message": "coordinates loaded"})
 }
 
 func validateTown(s string) (string, error) {
@@ -101,6 +383,16 @@ func (h *Handler) FixedDistance(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusUnprocessableEntity, "path must contain at least two towns")
 		return
 	}
+	stats, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
 	path := make([]string, len(req.Path))
 	for i, p := range req.Path {
 		t, err := validateTown(p)
@@ -110,12 +402,12 @@ func (h *Handler) FixedDistance(w http.ResponseWriter, r *http.Request) {
 		}
 		path[i] = t
 	}
-	dist, err := h.Graph.Distance(path)
+	dist, err := h.Graph.DistanceWithStats(path, stats)
 	if err != nil {
 		writeError(w, http.StatusNotFound, "NO SUCH ROUTE")
 		return
 	}
-	writeJSON(w, map[string]int{"distance": dist})
+	writeDistances(w, map[string]int{"distance": dist}, conv, stats)
 }
 
 func (h *Handler) CountByStops(w http.ResponseWriter, r *http.Request) {
@@ -148,8 +440,27 @@ func (h *Handler) CountByStops(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusUnprocessableEntity, "minStops cannot be greater than maxStops")
 		return
 	}
-	count := h.Graph.CountTripsByStops(from, to, minStops, maxStops)
-	json.NewEncoder(w).Encode(map[string]int{"count": count})
+	stats, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	opts, err := h.countOptions(req.TripOptions, stats)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	count, err := h.Graph.CountTripsByStopsContext(r.Context(), from, to, minStops, maxStops, opts)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeDistances(w, countResponse(count, opts.Trips), conv, stats)
 }
 
 func (h *Handler) CountByDistance(w http.ResponseWriter, r *http.Request) {
@@ -173,8 +484,55 @@ func (h *Handler) CountByDistance(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusUnprocessableEntity, "maxDistance must be > 0")
 		return
 	}
-	count := h.Graph.CountTripsByDistance(from, to, req.MaxDistance)
-	json.NewEncoder(w).Encode(map[string]int{"count": count})
+	stats, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	maxDistance, err := storedDistance(conv, req.MaxDistance)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	opts, err := h.countOptions(req.TripOptions, stats)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	count, err := h.Graph.CountTripsByDistanceContext(r.Context(), from, to, maxDistance, opts)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeDistances(w, countResponse(count, opts.Trips), conv, stats)
+}
+
+// countOptions builds the enumeration options of a count request.
+func (h *Handler) countOptions(t models.TripOptions, stats *graph.SearchStats) (graph.EnumOptions, error) {
+	opts := h.Enum
+	opts.Stats = stats
+	if t.TripLimit < 0 || t.TripLimit > graph.MaxTripLimit {
+		return opts, fmt.Errorf("tripLimit must be between 0 and %d", graph.MaxTripLimit)
+	}
+	if t.IncludeTrips {
+		opts.Trips = &graph.TripList{}
+		opts.TripLimit = t.TripLimit
+	}
+	return opts, nil
+}
+
+// countResponse is the body of the count endpoints, with the trips when
+// they were asked for.
+func countResponse(count int, trips *graph.TripList) interface{} {
+	if trips == nil {
+		return map[string]int{"count": count}
+	}
+	return map[string]interface{}{"count": count, "trips": trips.Trips, "truncated": trips.Truncated}
 }
 
 func (h *Handler) ShortestPath(w http.ResponseWriter, r *http.Request) {
@@ -190,12 +548,33 @@ func (h *Handler) ShortestPath(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusUnprocessableEntity, "invalid to: "+err.Error())
 		return
 	}
-	dist, path := h.Graph.ShortestPath(from, to)
+	alg, err := graph.ParseAlgorithm(r.URL.Query().Get("algorithm"))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if r.URL.Query().Get("algorithm") == "" && h.Graph.HierarchyEnabled() {
+		alg = graph.AlgorithmCH
+	}
+	explain, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	dist, path, stats := h.Graph.ShortestPathWith(from, to, alg)
 	if dist == -1 || len(path) == 0 {
 		writeError(w, http.StatusNotFound, "NO SUCH ROUTE")
 		return
 	}
-	writeJSON(w, map[string]interface{}{"distance": dist, "path": path})
+	if explain != nil {
+		explain = &stats
+	}
+	writeDistances(w, map[string]interface{}{"distance": dist, "path": path}, conv, explain)
 }
 
 func (h *Handler) SearchRoutes(w http.ResponseWriter, r *http.Request) {
@@ -216,6 +595,344 @@ func (h *Handler) SearchRoutes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res := h.Graph.SearchRoutes(from, to, req)
+	stats, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	req.Constraints.MaxDistance = conv.Stored(req.Constraints.MaxDistance)
+	opts := h.Enum
+	opts.Stats = stats
+	res, err := h.Graph.SearchRoutesContext(r.Context(), from, to, req, opts)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeDistances(w, res, conv, stats)
+}
+
+func (h *Handler) BottleneckPath(w http.ResponseWriter, r *http.Request) {
+	var req models.BottleneckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	from, err := validateTown(req.From)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	to, err := validateTown(req.To)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	mode, err := graph.ParseBottleneckMode(req.Mode)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	stats, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	path, dist, limit, ok := h.Graph.BottleneckPathWithStats(from, to, mode, stats)
+	if !ok {
+		writeError(w, http.StatusNotFound, "NO SUCH ROUTE")
+		return
+	}
+	writeDistances(w, map[string]interface{}{"mode": mode, "path": path, "distance": dist, "bottleneck": limit}, conv, stats)
+}
+
+func (h *Handler) DisjointPaths(w http.ResponseWriter, r *http.Request) {
+	from, err := validateTown(r.URL.Query().Get("from"))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid from: "+err.Error())
+		return
+	}
+	to, err := validateTown(r.URL.Query().Get("to"))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid to: "+err.Error())
+		return
+	}
+	if from == to {
+		writeError(w, http.StatusUnprocessableEntity, "from and to must be different towns")
+		return
+	}
+	mode, err := graph.ParseDisjointMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	stats, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	routes, ok := h.Graph.DisjointPathsWithStats(from, to, mode, stats)
+	if !ok {
+		writeError(w, http.StatusNotFound, "NO SUCH ROUTE")
+		return
+	}
+	writeDistances(w, map[string]interface{}{
+		"mode":          mode,
+		"routes":        routes,
+		"totalDistance": routes[0].Distance + routes[1].Distance,
+	}, conv, stats)
+}
+
+func (h *Handler) SampleTrips(w http.ResponseWriter, r *http.Request) {
+	var req models.SampleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	from, err := validateTown(req.From)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	to, err := validateTown(req.To)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	mode, err := graph.ParseSampleMode(req.Mode)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	stats, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	maxDistance, err := storedDistance(conv, req.MaxDistance)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	opts := graph.SampleOptions{
+		Mode:        mode,
+		MinStops:    req.MinStops,
+		MaxStops:    req.MaxStops,
+		MaxDistance: maxDistance,
+		Count:       req.Count,
+		Seed:        time.Now().UnixNano(),
+		Stats:       stats,
+	}
+	// same defaults as the count endpoints
+	if opts.MinStops == 0 {
+		opts.MinStops = 1
+	}
+	if opts.Count == 0 {
+		opts.Count = 1
+	}
+	if req.Seed != nil {
+		opts.Seed = *req.Seed
+	}
+	sample, err := h.Graph.SampleTrips(from, to, opts)
+	if errors.Is(err, graph.ErrNoSuchRoute) {
+		writeError(w, http.StatusNotFound, "NO SUCH ROUTE")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeDistances(w, sample, conv, stats)
+}
+
+func (h *Handler) DiverseRoutes(w http.ResponseWriter, r *http.Request) {
+	var req models.DiverseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	from, err := validateTown(req.From)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	to, err := validateTown(req.To)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	stats, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	routes, err := h.Graph.DiverseRoutes(from, to, graph.DiverseOptions{
+		Count:      req.Count,
+		MaxOverlap: req.MaxOverlap,
+		Penalty:    req.Penalty,
+		Stats:      stats,
+	})
+	if errors.Is(err, graph.ErrNoSuchRoute) {
+		writeError(w, http.StatusNotFound, "NO SUCH ROUTE")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeDistances(w, map[string][]graph.DiverseRoute{"routes": routes}, conv, stats)
+}
+
+func (h *Handler) ShortestPathTree(w http.ResponseWriter, r *http.Request) {
+	from, err := validateTown(r.URL.Query().Get("from"))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid from: "+err.Error())
+		return
+	}
+	withPaths := false
+	if raw := r.URL.Query().Get("paths"); raw != "" {
+		withPaths, err = strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "paths must be true or false")
+			return
+		}
+	}
+	stats, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	tree, ok := h.Graph.ShortestPathTreeWithStats(from, withPaths, stats)
+	if !ok {
+		writeError(w, http.StatusNotFound, "NO SUCH ROUTE")
+		return
+	}
+	writeDistances(w, tree, conv, stats)
+}
+
+func (h *Handler) MaxFlow(w http.ResponseWriter, r *http.Request) {
+	if !noUnit(w, r) {
+		return
+	}
+	var req models.MaxFlowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	from, err := validateTown(req.From)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	to, err := validateTown(req.To)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if from == to {
+		writeError(w, http.StatusUnprocessableEntity, "from and to must be different towns")
+		return
+	}
+	res, ok := h.Graph.MaxFlow(from, to)
+	if !ok {
+		writeError(w, http.StatusNotFound, "NO SUCH ROUTE")
+		return
+	}
 	writeJSON(w, res)
 }
+
+func (h *Handler) CriticalElements(w http.ResponseWriter, r *http.Request) {
+	if !noUnit(w, r) {
+		return
+	}
+	fromRaw := r.URL.Query().Get("from")
+	if fromRaw == "" {
+		writeJSON(w, h.Graph.CriticalUndirected())
+		return
+	}
+	from, err := validateTown(fromRaw)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid from: "+err.Error())
+		return
+	}
+	report, err := h.Graph.CriticalFrom(from)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "NO SUCH ROUTE")
+		return
+	}
+	writeJSON(w, report)
+}
+
+func (h *Handler) PlanTour(w http.ResponseWriter, r *http.Request) {
+	var req models.TourRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	start, err := validateTown(req.Start)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if len(req.Towns) == 0 {
+		writeError(w, http.StatusUnprocessableEntity, "towns must contain at least one town")
+		return
+	}
+	towns := make([]string, len(req.Towns))
+	for i, t := range req.Towns {
+		towns[i], err = validateTown(t)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+	}
+	stats, err := explainStats(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	conv, err := h.distanceUnit(r)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	tour, err := h.Graph.PlanTour(start, towns, graph.TourOptions{Return: req.ReturnToStart, ExactLimit: req.ExactLimit, Stats: stats})
+	if errors.Is(err, graph.ErrNoSuchRoute) {
+		writeError(w, http.StatusNotFound, "NO SUCH ROUTE")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeDistances(w, tour, conv, stats)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	graph "github.com/aashi1008/hamburg-rails/internal/graphs"
+	"github.com/stretchr/testify/assert"
+)
+
+// kmHandler serves a graph declared in kilometres, with decimal distances.
+func kmHandler(t *testing.T) *Handler {
+	g := graph.NewGraph()
+	_, err := g.ImportWithOptions(strings.NewReader("@units km\nAB2.5, BC1.25, AC5, CA1"), graph.FormatText, graph.ImportOptions{})
+	assert.NoError(t, err)
+	return NewHandler(g)
+}
+
+func serve(handler http.HandlerFunc, method, target, body string) (int, map[string]interface{}) {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	var fields map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &fields)
+	return rec.Code, fields
+}
+
+func TestShortestPathUnits(t *testing.T) {
+	h := kmHandler(t)
+
+	code, body := serve(h.ShortestPath, http.MethodGet, "/routes/shortest?from=A&to=C", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"distance": 3.75, "path": []interface{}{"A", "B", "C"}, "unit": "km"}, body)
+
+	code, body = serve(h.ShortestPath, http.MethodGet, "/routes/shortest?from=A&to=C&unit=m", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 3750.0, body["distance"])
+	assert.Equal(t, "m", body["unit"])
+
+	code, body = serve(h.ShortestPath, http.MethodGet, "/routes/shortest?from=A&to=C&unit=miles", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2.33, body["distance"])
+	assert.Equal(t, "mi", body["unit"])
+
+	code, body = serve(h.ShortestPath, http.MethodGet, "/routes/shortest?from=A&to=C&unit=min", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "cannot convert km to min", body["error"])
+}
+
+func TestShortestPathTreeUnits(t *testing.T) {
+	h := kmHandler(t)
+
+	code, body := serve(h.ShortestPathTree, http.MethodGet, "/routes/tree?from=A&unit=m", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{
+		"from": "A",
+		"towns": []interface{}{
+			map[string]interface{}{"town": "A", "distance": 0.0},
+			map[string]interface{}{"town": "B", "distance": 2500.0, "predecessor": "A"},
+			map[string]interface{}{"town": "C", "distance": 3750.0, "predecessor": "B"},
+		},
+		"unit": "m",
+	}, body)
+}
+
+func TestCountByDistanceUnits(t *testing.T) {
+	h := kmHandler(t)
+
+	// A->B->C at 3.75 km and A->C at 5 km are shorter than 5.5 km
+	code, body := serve(h.CountByDistance, http.MethodPost, "/routes/count-by-distance", `{"from":"A","to":"C","maxDistance":5.5}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"count": 2.0, "unit": "km"}, body)
+
+	code, body = serve(h.CountByDistance, http.MethodPost, "/routes/count-by-distance?unit=m", `{"from":"A","to":"C","maxDistance":4000}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"count": 1.0, "unit": "m"}, body)
+
+	// the limit is rounded up to the next stored step, so 5000.1 m
+	// takes in the 5 km edge
+	code, body = serve(h.CountByDistance, http.MethodPost, "/routes/count-by-distance?unit=m", `{"from":"A","to":"C","maxDistance":5000.1}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2.0, body["count"])
+
+	code, body = serve(h.CountByDistance, http.MethodPost, "/routes/count-by-distance?unit=m", `{"from":"A","to":"C","maxDistance":1e300}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, "maxDistance is too large", body["error"])
+}
+
+func TestCriticalElementsUnknownTown(t *testing.T) {
+	h := kmHandler(t)
+
+	code, body := serve(h.CriticalElements, http.MethodGet, "/graph/critical?from=X", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "NO SUCH ROUTE", body["error"])
+
+	code, body = serve(h.CriticalElements, http.MethodGet, "/graph/critical?from=A", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "directed", body["view"])
+}
+
+func TestUnitRejectedWithoutDistances(t *testing.T) {
+	h := kmHandler(t)
+	for name, handler := range map[string]http.HandlerFunc{
+		"/admin/graph":          h.LoadGraph,
+		"/admin/graph/validate": h.ValidateGraph,
+		"/analysis/max-flow":    h.MaxFlow,
+		"/graph/critical":       h.CriticalElements,
+	} {
+		code, body := serve(handler, http.MethodPost, name+"?unit=m", `{"from":"A","to":"C"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, code, name)
+		assert.Equal(t, "unit is not supported by this endpoint", body["error"], name)
+	}
+	// the graph was not replaced
+	assert.Equal(t, "km", h.Graph.Metadata().Units)
+}
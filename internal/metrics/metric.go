@@ -21,9 +21,17 @@ var (
 			Help: "Total number of graph loads",
 		},
 	)
+
+	GraphCHPreprocessingSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "graph_ch_preprocessing_seconds",
+			Help: "Time taken to build the contraction hierarchy for the current graph",
+		},
+	)
 )
 
 func init() {
 	CustomRegistry.MustRegister(GraphNodesTotal)
 	CustomRegistry.MustRegister(GraphLoadsTotal)
+	CustomRegistry.MustRegister(GraphCHPreprocessingSeconds)
 }
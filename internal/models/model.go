@@ -9,18 +9,27 @@ type CountByStopsRequest struct {
     To       string `json:"to"`
     MinStops int    `json:"minStops"`
     MaxStops int    `json:"maxStops"`
+    TripOptions
 }
 
 type CountByDistanceRequest struct {
-    From        string `json:"from"`
-    To          string `json:"to"`
-    MaxDistance int    `json:"maxDistance"`
+    From        string  `json:"from"`
+    To          string  `json:"to"`
+    MaxDistance float64 `json:"maxDistance"`
+    TripOptions
+}
+
+// TripOptions asks a count endpoint to list the trips it counted.
+type TripOptions struct {
+	IncludeTrips bool `json:"includeTrips"`
+	// TripLimit caps the listed trips; zero means the server default.
+	TripLimit int `json:"tripLimit"`
 }
 
 type RouteSearchConstraints struct {
-	MaxStops      int  `json:"maxStops,omitempty"`
-	MaxDistance   int  `json:"maxDistance,omitempty"`
-	DistinctNodes bool `json:"distinctNodes,omitempty"`
+	MaxStops      int     `json:"maxStops,omitempty"`
+	MaxDistance   float64 `json:"maxDistance,omitempty"`
+	DistinctNodes bool    `json:"distinctNodes,omitempty"`
 }
 
 type RouteSearchRequest struct {
@@ -35,4 +44,47 @@ type RouteSearchResponse struct {
 		Path     []string `json:"path"`
 		Distance int      `json:"distance"`
 	} `json:"routes"`
-}
\ No newline at end of file
+}
+
+type BottleneckRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Mode string `json:"mode,omitempty"`
+}
+
+type MaxFlowRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type TourRequest struct {
+	Start         string   `json:"start"`
+	Towns         []string `json:"towns"`
+	ReturnToStart bool     `json:"returnToStart"`
+	// ExactLimit is nil when not given, so that 0 can turn the exact
+	// solver off.
+	ExactLimit *int `json:"exactLimit,omitempty"`
+}
+
+type SampleRequest struct {
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Mode        string  `json:"mode,omitempty"`
+	MinStops    int     `json:"minStops"`
+	MaxStops    int     `json:"maxStops"`
+	MaxDistance float64 `json:"maxDistance"`
+	Count       int     `json:"count"`
+	// Seed makes the sample reproducible; when absent a random seed is
+	// used and returned.
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+type DiverseRequest struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count,omitempty"`
+	// MaxOverlap bounds the distance any two routes share, as a fraction of
+	// the shorter one.
+	MaxOverlap float64 `json:"maxOverlap,omitempty"`
+	Penalty    float64 `json:"penalty,omitempty"`
+}
@@ -16,12 +16,22 @@ func SetupRoutes(h *handlers.Handler, logger *slog.Logger) *mux.Router {
 	r.Use(middleware.LoggingMiddleware(logger))
 	r.HandleFunc("/healthz", h.Healthz).Methods(http.MethodGet)
 	r.HandleFunc("/admin/graph", h.LoadGraph).Methods(http.MethodPost)
+	r.HandleFunc("/admin/graph/validate", h.ValidateGraph).Methods(http.MethodPost)
+	r.HandleFunc("/admin/graph/coordinates", h.SetCoordinates).Methods(http.MethodPut)
 	r.HandleFunc("/graph", h.CurrentEdgeList).Methods(http.MethodGet)
+	r.HandleFunc("/graph/critical", h.CriticalElements).Methods(http.MethodGet)
 	r.HandleFunc("/routes/distance", h.FixedDistance).Methods(http.MethodPost)
 	r.HandleFunc("/routes/count-by-stops", h.CountByStops).Methods(http.MethodPost)
 	r.HandleFunc("/routes/count-by-distance", h.CountByDistance).Methods(http.MethodPost)
 	r.HandleFunc("/routes/shortest", h.ShortestPath).Methods(http.MethodGet)
 	r.Handle("/metrics", promhttp.HandlerFor(metrics.CustomRegistry, promhttp.HandlerOpts{}))
 	r.HandleFunc("/routes/search", h.SearchRoutes).Methods(http.MethodPost)
+	r.HandleFunc("/routes/bottleneck", h.BottleneckPath).Methods(http.MethodPost)
+	r.HandleFunc("/routes/disjoint", h.DisjointPaths).Methods(http.MethodGet)
+	r.HandleFunc("/routes/tour", h.PlanTour).Methods(http.MethodPost)
+	r.HandleFunc("/routes/tree", h.ShortestPathTree).Methods(http.MethodGet)
+	r.HandleFunc("/routes/sample", h.SampleTrips).Methods(http.MethodPost)
+	r.HandleFunc("/routes/diverse", h.DiverseRoutes).Methods(http.MethodPost)
+	r.HandleFunc("/analysis/max-flow", h.MaxFlow).Methods(http.MethodPost)
 	return r
 }